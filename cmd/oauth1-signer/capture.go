@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The harFile holds the part of an HTTP Archive read by the verify command,
+// see https://w3c.github.io/web-performance/specs/HAR/Overview.html
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method  string `json:"method"`
+				URL     string `json:"url"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// The parseCapturedRequest parses a raw HTTP request dump or, for JSON
+// data, the request of the given entry of an HTTP Archive. The scheme and
+// the host of requests captured on the server side are taken from origin,
+// which defaults to https and the Host header.
+func parseCapturedRequest(data []byte, entry int, origin string) (*http.Request, error) {
+	var req *http.Request
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		req, err = parseHAREntry(data, entry)
+	} else {
+		req, err = parseRequestDump(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil {
+			return nil, fmt.Errorf("invalid origin: %w", err)
+		}
+		req.URL.Scheme, req.URL.Host = u.Scheme, u.Host
+	} else if !req.URL.IsAbs() {
+		req.URL.Scheme, req.URL.Host = "https", req.Host
+	}
+	return req, nil
+}
+
+// The parseHAREntry returns the request of an HTTP Archive entry.
+func parseHAREntry(data []byte, entry int) (*http.Request, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid HAR file: %w", err)
+	}
+	if entry < 0 || entry >= len(har.Log.Entries) {
+		return nil, fmt.Errorf("HAR entry %v not found, %v entries", entry, len(har.Log.Entries))
+	}
+	captured := har.Log.Entries[entry].Request
+	var body []byte
+	if captured.PostData != nil {
+		body = []byte(captured.PostData.Text)
+	}
+	req, err := http.NewRequest(captured.Method, captured.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range captured.Headers {
+		// HTTP/2 pseudo-headers such as :authority are not headers
+		if !strings.HasPrefix(header.Name, ":") {
+			req.Header.Add(header.Name, header.Value)
+		}
+	}
+	if captured.PostData != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", captured.PostData.MimeType)
+	}
+	return req, nil
+}
+
+// The parseRequestDump parses a raw HTTP/1.x request. Dumps without
+// Content-Length or Transfer-Encoding headers have the rest of the data
+// as body.
+func parseRequestDump(data []byte) (*http.Request, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	req, err := http.ReadRequest(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP request dump: %w", err)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP request dump: %w", err)
+	}
+	if req.Header.Get("Content-Length") == "" && len(req.TransferEncoding) == 0 {
+		rest, _ := io.ReadAll(r)
+		body = append(body, rest...)
+	}
+	// client side requests have neither RequestURI nor unread bodies
+	captured, err := http.NewRequest(req.Method, req.URL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	captured.Header, captured.Host = req.Header, req.Host
+	return captured, nil
+}
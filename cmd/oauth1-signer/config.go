@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The config holds the options of a command, read from a JSON or YAML file
+// and from flags:
+//
+//	consumerKey: "<consumer key>"
+//	keyFile: signing_key.p12
+//	keyPassword: file:///run/secrets/key_password
+//	method: POST
+//	url: https://sandbox.api.mastercard.com/service
+//	bodyFile: payload.json
+//	contentType: application/json
+//	certificate: consumer.crt # verify only
+type config struct {
+	ConsumerKey  string `json:"consumerKey" yaml:"consumerKey"`
+	KeyFile      string `json:"keyFile" yaml:"keyFile"`
+	KeyPassword  string `json:"keyPassword" yaml:"keyPassword"`
+	KeyAlias     string `json:"keyAlias" yaml:"keyAlias"`
+	Method       string `json:"method" yaml:"method"`
+	URL          string `json:"url" yaml:"url"`
+	BodyFile     string `json:"bodyFile" yaml:"bodyFile"`
+	ContentType  string `json:"contentType" yaml:"contentType"`
+	HashFormBody bool   `json:"hashFormBody" yaml:"hashFormBody"`
+	Certificate  string `json:"certificate" yaml:"certificate"`
+}
+
+// The parseConfig parses the flags of a command and the -config file, if
+// any. Flags set explicitly take precedence over the values of the file.
+func parseConfig(fs *flag.FlagSet, cfg *config, args []string) error {
+	configFile := fs.String("config", "", "JSON or YAML file holding the options")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *configFile == "" {
+		return nil
+	}
+	loaded, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	merge(cfg, loaded, explicit)
+	return nil
+}
+
+// The loadConfig reads a JSON or YAML file, depending on its extension.
+// Relative file paths are resolved against the directory of the file.
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var loaded config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &loaded)
+	default:
+		err = json.Unmarshal(data, &loaded)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %v: %w", path, err)
+	}
+	for _, file := range []*string{&loaded.KeyFile, &loaded.BodyFile, &loaded.Certificate} {
+		if *file != "" && *file != "-" && !filepath.IsAbs(*file) {
+			*file = filepath.Join(filepath.Dir(path), *file)
+		}
+	}
+	return &loaded, nil
+}
+
+// The merge copies the values set in src to dst, except for the options
+// whose flag was set explicitly.
+func merge(dst, src *config, explicit map[string]bool) {
+	for _, field := range []struct {
+		flag     string
+		dst, src *string
+	}{
+		{"consumer-key", &dst.ConsumerKey, &src.ConsumerKey},
+		{"key-file", &dst.KeyFile, &src.KeyFile},
+		{"key-password", &dst.KeyPassword, &src.KeyPassword},
+		{"key-alias", &dst.KeyAlias, &src.KeyAlias},
+		{"method", &dst.Method, &src.Method},
+		{"url", &dst.URL, &src.URL},
+		{"body-file", &dst.BodyFile, &src.BodyFile},
+		{"content-type", &dst.ContentType, &src.ContentType},
+		{"cert", &dst.Certificate, &src.Certificate},
+	} {
+		if *field.src != "" && !explicit[field.flag] {
+			*field.dst = *field.src
+		}
+	}
+	if !explicit["hash-form-body"] {
+		dst.HashFormBody = dst.HashFormBody || src.HashFormBody
+	}
+}
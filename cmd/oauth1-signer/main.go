@@ -0,0 +1,53 @@
+// Command oauth1-signer signs requests to Mastercard APIs and prints the
+// Authorization header, the signature base string and a curl command, for
+// debugging API calls without writing Go code. It also verifies requests
+// captured in logs or HAR files and reports the step that failed.
+//
+// Usage:
+//
+//	oauth1-signer [sign] [flags]
+//	oauth1-signer verify [flags] <HTTP request dump or HAR file>
+//
+// Options can also be read from a JSON or YAML file given with -config, in
+// which case flags take precedence over the values of the file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage:
+  oauth1-signer [sign] [flags]  sign a request and print the Authorization header,
+                                the signature base string and a curl command
+  oauth1-signer verify [flags] <file>
+                                verify a raw HTTP request dump or a HAR entry and
+                                report the step that failed, if any
+
+Run "oauth1-signer <command> -h" for the flags.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// The run executes the command given in args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	command := "sign"
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "sign":
+		return runSign(args, stdin, stdout, stderr)
+	case "verify":
+		return runVerify(args, stdin, stdout, stderr)
+	case "help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "oauth1-signer: unknown command %q\n\n%v", command, usage)
+		return 2
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"errors"
+	"flag"
+	"fmt"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultContentType = "application/json"
+
+// The runSign signs a request and prints the Authorization header, the
+// signature base string and a curl command sending the request.
+func runSign(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cfg := config{Method: http.MethodGet}
+	fs.StringVar(&cfg.ConsumerKey, "consumer-key", cfg.ConsumerKey, "consumer key")
+	fs.StringVar(&cfg.KeyFile, "key-file", cfg.KeyFile, "PKCS#12 or PEM signing key file")
+	fs.StringVar(&cfg.KeyPassword, "key-password", cfg.KeyPassword, `password of the signing key, inline, "base64:<value>" or "file://<path>"`)
+	fs.StringVar(&cfg.KeyAlias, "key-alias", cfg.KeyAlias, "alias of the key in the PKCS#12 container, if several")
+	fs.StringVar(&cfg.Method, "method", cfg.Method, "HTTP method")
+	fs.StringVar(&cfg.URL, "url", cfg.URL, "request URL")
+	fs.StringVar(&cfg.BodyFile, "body-file", cfg.BodyFile, `file holding the request body, "-" for the standard input`)
+	fs.StringVar(&cfg.ContentType, "content-type", cfg.ContentType, "Content-Type of the body (default "+defaultContentType+")")
+	fs.BoolVar(&cfg.HashFormBody, "hash-form-body", cfg.HashFormBody, "hash form-encoded bodies instead of signing their parameters")
+	timestamp := fs.Int64("timestamp", 0, "oauth_timestamp to sign with, for reproducible signatures")
+	nonce := fs.String("nonce", "", "oauth_nonce to sign with, for reproducible signatures")
+	if err := parseConfig(fs, &cfg, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "oauth1-signer: %v\n", err)
+		return 2
+	}
+
+	signingKey, err := loadSigningKey(&cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "oauth1-signer: %v\n", err)
+		return 1
+	}
+	body, err := readBody(cfg.BodyFile, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "oauth1-signer: %v\n", err)
+		return 1
+	}
+	req, err := newRequest(&cfg, body)
+	if err != nil {
+		fmt.Fprintf(stderr, "oauth1-signer: %v\n", err)
+		return 1
+	}
+	signer := &oauth.Signer{ConsumerKey: cfg.ConsumerKey, SigningKey: signingKey, HashFormBody: cfg.HashFormBody}
+	if *timestamp != 0 {
+		signer.Clock = oauth.ClockFunc(func() time.Time { return time.Unix(*timestamp, 0) })
+	}
+	if *nonce != "" {
+		signer.NonceGenerator = oauth.NonceFunc(func() (string, error) { return *nonce, nil })
+	}
+	details, err := signer.SignWithDetails(req)
+	if err != nil {
+		fmt.Fprintf(stderr, "oauth1-signer: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "%v: %v\n\n", oauth.AuthorizationHeaderName, req.Header.Get(oauth.AuthorizationHeaderName))
+	if details.BodyHash != "" {
+		fmt.Fprintf(stdout, "Body hash:\n%v\n\n", details.BodyHash)
+	}
+	fmt.Fprintf(stdout, "Signature base string:\n%v\n\n", details.SignatureBaseString)
+	fmt.Fprintln(stdout, curlCommand(req, cfg.BodyFile, body))
+	return 0
+}
+
+// The loadSigningKey loads the PKCS#12 or PEM signing key of the config.
+func loadSigningKey(cfg *config) (*rsa.PrivateKey, error) {
+	if cfg.ConsumerKey == "" {
+		return nil, errors.New("provide the consumer key with -consumer-key")
+	}
+	if cfg.KeyFile == "" {
+		return nil, errors.New("provide the signing key with -key-file")
+	}
+	data, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	password, err := utils.ResolveSecretString(cfg.KeyPassword)
+	if err != nil {
+		return nil, err
+	}
+	return utils.LoadSigningKeyFromBytes(data, password, cfg.KeyAlias)
+}
+
+// The readBody reads the body file, or the standard input for "-".
+func readBody(bodyFile string, stdin io.Reader) ([]byte, error) {
+	switch bodyFile {
+	case "":
+		return nil, nil
+	case "-":
+		return io.ReadAll(stdin)
+	default:
+		return os.ReadFile(bodyFile)
+	}
+}
+
+// The newRequest returns the request of the config. Bodies are sent as
+// JSON unless another content type is given.
+func newRequest(cfg *config, body []byte) (*http.Request, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("provide the request URL with -url")
+	}
+	req, err := http.NewRequest(strings.ToUpper(cfg.Method), cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType := cfg.ContentType; contentType != "" || len(body) > 0 {
+		if contentType == "" {
+			contentType = defaultContentType
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// The curlCommand returns a curl command sending the signed request. Bodies
+// read from a file are sent from that file.
+func curlCommand(req *http.Request, bodyFile string, body []byte) string {
+	var b strings.Builder
+	b.WriteString("curl")
+	if req.Method != http.MethodGet || len(body) > 0 {
+		b.WriteString(" -X " + req.Method)
+	}
+	b.WriteString(" " + shellQuote(req.URL.String()))
+	for _, name := range []string{oauth.AuthorizationHeaderName, "Content-Type"} {
+		if value := req.Header.Get(name); value != "" {
+			b.WriteString(" -H " + shellQuote(name+": "+value))
+		}
+	}
+	if len(body) > 0 {
+		if bodyFile != "" && bodyFile != "-" {
+			b.WriteString(" --data-binary " + shellQuote("@"+bodyFile))
+		} else {
+			b.WriteString(" --data-binary " + shellQuote(string(body)))
+		}
+	}
+	return b.String()
+}
+
+// The shellQuote quotes a value for POSIX shells.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	consumerKey = "WFQHgP6gI01ZxbpqUzdWQ_GpDVrym3dhY6Q9X3PZe4ba3850!3b9f3d6593d04a0cbefadaf8bb3975fb0000000000000000"
+)
+
+var signingKey, _ = utils.LoadSigningKey("../../testdata/test_key_container.p12", "Password1")
+
+// The outputLine returns the line following the given heading.
+func outputLine(output, heading string) string {
+	_, after, _ := strings.Cut(output, heading+"\n")
+	line, _, _ := strings.Cut(after, "\n")
+	return line
+}
+
+func TestRunSign_ShouldPrintHeaderBaseStringAndCurlCommand(t *testing.T) {
+
+	// GIVEN
+	var stdout, stderr bytes.Buffer
+	args := []string{"sign", "-consumer-key", consumerKey, "-key-file", "../../testdata/test_key_container.p12", "-key-password", "Password1",
+		"-method", "post", "-url", "https://sandbox.api.mastercard.com/service?a=1", "-body-file", "-", "-timestamp", "1111111111", "-nonce", "nonce"}
+
+	// WHEN
+	code := run(args, strings.NewReader(`{"foo":"bar"}`), &stdout, &stderr)
+
+	// THEN
+	if code != 0 {
+		t.Fatalf("Expected the request to be signed, got %v %v", code, stderr.String())
+	}
+	output := stdout.String()
+	authHeader := strings.TrimPrefix(strings.SplitN(output, "\n", 2)[0], "Authorization: ")
+	u, _ := url.Parse("https://sandbox.api.mastercard.com/service?a=1")
+	if err := oauth.VerifyAuthorizationHeader(authHeader, u, "POST", []byte(`{"foo":"bar"}`), &signingKey.PublicKey); err != nil {
+		t.Errorf("Expected a valid Authorization header, got %v", err)
+	}
+	if sbs := outputLine(output, "Signature base string:"); !strings.HasPrefix(sbs, "POST&https%3A%2F%2Fsandbox.api.mastercard.com%2Fservice&a%3D1%26oauth_body_hash%3D") ||
+		!strings.Contains(sbs, "oauth_nonce%3Dnonce%26oauth_signature_method%3DRSA-SHA256%26oauth_timestamp%3D1111111111") {
+		t.Errorf("Expected the signature base string, got %v", sbs)
+	}
+	expected := "curl -X POST 'https://sandbox.api.mastercard.com/service?a=1' -H 'Authorization: " + authHeader +
+		"' -H 'Content-Type: application/json' --data-binary '{\"foo\":\"bar\"}'"
+	if curl := strings.TrimSpace(output[strings.LastIndex(output, "\n\n")+2:]); curl != expected {
+		t.Errorf("Expected %v, got %v", expected, curl)
+	}
+}
+
+func TestRunSign_ShouldReadConfigFile(t *testing.T) {
+
+	// GIVEN
+	dir := t.TempDir()
+	key, _ := os.ReadFile("../../testdata/test_key_pkcs8.pem")
+	_ = os.WriteFile(filepath.Join(dir, "key.pem"), key, 0600)
+	_ = os.WriteFile(filepath.Join(dir, "payload"), []byte("amount=10.00"), 0600)
+	config := "consumerKey: " + consumerKey + "\nkeyFile: key.pem\nmethod: PUT\nurl: https://example.com/original\n" +
+		"bodyFile: payload\ncontentType: application/x-www-form-urlencoded\n"
+	_ = os.WriteFile(filepath.Join(dir, "oauth1-signer.yaml"), []byte(config), 0600)
+	var stdout, stderr bytes.Buffer
+
+	// WHEN
+	code := run([]string{"-config", filepath.Join(dir, "oauth1-signer.yaml"), "-url", "https://sandbox.api.mastercard.com/service"}, nil, &stdout, &stderr)
+
+	// THEN
+	if code != 0 {
+		t.Fatalf("Expected the request to be signed, got %v %v", code, stderr.String())
+	}
+	output := stdout.String()
+	if sbs := outputLine(output, "Signature base string:"); !strings.HasPrefix(sbs, "PUT&https%3A%2F%2Fsandbox.api.mastercard.com%2Fservice&amount%3D10.00%26oauth_consumer_key") {
+		t.Errorf("Expected the flags to override the config file and the form to be signed, got %v", sbs)
+	}
+	if strings.Contains(output, "Body hash:") || !strings.Contains(output, "--data-binary '@"+filepath.Join(dir, "payload")+"'") {
+		t.Errorf("Expected the form body to be sent from its file, got %v", output)
+	}
+}
+
+func TestRunSign_ShouldLetExplicitFlagsOverrideConfigFile(t *testing.T) {
+
+	// GIVEN
+	dir := t.TempDir()
+	config := `{"consumerKey": "` + consumerKey + `", "method": "POST", "url": "https://sandbox.api.mastercard.com/service",
+		"contentType": "application/x-www-form-urlencoded", "hashFormBody": true}`
+	path := filepath.Join(dir, "oauth1-signer.json")
+	_ = os.WriteFile(path, []byte(config), 0600)
+
+	tests := []struct {
+		args     []string
+		hashed   bool
+		expected string
+	}{
+		{nil, true, "the config file to hash the form body"},
+		{[]string{"-hash-form-body=false"}, false, "-hash-form-body=false to override the config file"},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"sign", "-config", path, "-key-file", "../../testdata/test_key_pkcs8.pem", "-body-file", "-"}, test.args...)
+
+		// WHEN
+		code := run(args, strings.NewReader("amount=10.00"), &stdout, &stderr)
+
+		// THEN
+		if code != 0 {
+			t.Fatalf("Expected the request to be signed, got %v %v", code, stderr.String())
+		}
+		if hashed := strings.Contains(stdout.String(), "Body hash:"); hashed != test.hashed {
+			t.Errorf("Expected %v, got %v", test.expected, stdout.String())
+		}
+	}
+}
+
+func TestRun_ShouldFailOnInvalidInput(t *testing.T) {
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"unknown"}, 2},
+		{[]string{"sign", "-unknown"}, 2},
+		{[]string{"sign", "-url", "https://example.com"}, 1},
+		{[]string{"sign", "-consumer-key", consumerKey, "-key-file", "../../testdata/test_key_container.p12", "-key-password", "Password1"}, 1},
+		{[]string{"sign", "-config", "missing.json"}, 2},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+
+		// WHEN
+		code := run(test.args, nil, &stdout, &stderr)
+
+		// THEN
+		if code != test.code || stderr.Len() == 0 {
+			t.Errorf("Expected exit code %v and an error for %v, got %v", test.code, test.args, code)
+		}
+	}
+}
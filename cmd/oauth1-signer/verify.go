@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/rsa"
+	"errors"
+	"flag"
+	"fmt"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The runVerify verifies a captured request against the certificate of its
+// consumer and reports the step that failed, if any.
+func runVerify(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: oauth1-signer verify [flags] <HTTP request dump or HAR file, - for the standard input>")
+		fs.PrintDefaults()
+	}
+	var cfg config
+	fs.StringVar(&cfg.Certificate, "cert", cfg.Certificate, "X.509 certificate or PEM public key of the consumer")
+	fs.BoolVar(&cfg.HashFormBody, "hash-form-body", cfg.HashFormBody, "expect form-encoded bodies to be hashed instead of signed")
+	origin := fs.String("origin", "", "scheme and host the request was signed for, such as https://api.example.com (default https and the Host header)")
+	entry := fs.Int("entry", 0, "index of the HAR entry to verify")
+	if err := parseConfig(fs, &cfg, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "oauth1-signer: %v\n", err)
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if cfg.Certificate == "" {
+		fmt.Fprintln(stderr, "oauth1-signer: provide the certificate of the consumer with -cert")
+		return 2
+	}
+
+	data, err := readBody(fs.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "oauth1-signer: %v\n", err)
+		return 1
+	}
+	req, err := parseCapturedRequest(data, *entry, *origin)
+	if err != nil {
+		fmt.Fprintf(stderr, "oauth1-signer: %v\n", err)
+		return 1
+	}
+	publicKey, err := utils.LoadPublicKeyEntry(cfg.Certificate)
+	if err != nil {
+		fmt.Fprintf(stderr, "oauth1-signer: %v\n", err)
+		return 1
+	}
+	report := &verifyReport{w: stdout}
+	if !report.verify(req, publicKey, cfg.HashFormBody) {
+		return 1
+	}
+	return 0
+}
+
+// The verifyReport prints the steps of the verification of a request.
+type verifyReport struct {
+	w io.Writer
+}
+
+func (r *verifyReport) step(name string, value interface{}) {
+	fmt.Fprintf(r.w, "%-23v %v\n", name+":", value)
+}
+
+// The fail prints the step the verification failed at.
+func (r *verifyReport) fail(step string, cause interface{}) bool {
+	fmt.Fprintf(r.w, "\nFAILED at %v: %v\n", step, cause)
+	return false
+}
+
+// The verify verifies the request step by step, recomputing the body hash
+// and the signature base string, and reports whether it is valid.
+func (r *verifyReport) verify(req *http.Request, publicKey *utils.PublicKeyEntry, hashFormBody bool) bool {
+	r.step("Request", req.Method+" "+req.URL.String())
+	authHeader := req.Header.Get(oauth.AuthorizationHeaderName)
+	if authHeader == "" {
+		return r.fail("Authorization header", oauth.ErrMissingAuthorizationHeader)
+	}
+	params, err := oauth.ParseAuthorizationHeader(authHeader)
+	if err != nil {
+		return r.fail("Authorization header", err)
+	}
+	r.step("Consumer key", params.ConsumerKey())
+	r.step("Timestamp", formatTimestamp(params.Timestamp()))
+	r.step("Nonce", params.Nonce())
+	if cert := publicKey.Certificate; cert != nil {
+		validity := fmt.Sprintf("%v, valid from %v to %v", cert.Subject, cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339))
+		if now := time.Now(); now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+			validity += " (" + utils.ErrCertificateExpired.Error() + ")"
+		}
+		r.step("Certificate", validity)
+	}
+
+	signatureMethod := newSignatureMethod(params.SignatureMethod(), publicKey.PublicKey)
+	if signatureMethod == nil {
+		return r.fail("Signature method", fmt.Errorf("%w: %v", oauth.ErrUnsupportedSignatureMethod, params.SignatureMethod()))
+	}
+	r.step("Signature method", signatureMethod.Name())
+	verifier := &oauth.Verifier{PublicKey: publicKey.PublicKey, Method: signatureMethod, HashFormBody: hashFormBody}
+	details, err := verifier.VerifyWithDetails(req)
+	if details == nil {
+		return r.fail("Authorization header", err)
+	}
+
+	// the body hash is checked first, then the signature of the base string
+	bodyHashErr := err
+	if !errors.Is(err, oauth.ErrBodyHashMismatch) && !errors.Is(err, oauth.ErrMissingParameter) {
+		bodyHashErr = nil
+	}
+	received, _ := params.Lookup("oauth_body_hash")
+	switch {
+	case details.BodyHash == "" && bodyHashErr == nil:
+		r.step("Body hash", "none, the form parameters are signed")
+	case bodyHashErr == nil:
+		r.step("Body hash", "ok "+received)
+	default:
+		r.step("Body hash", "MISMATCH")
+		r.step("  received", received)
+		if details.BodyHash == "" {
+			r.step("  computed", "none, the form parameters are signed unless -hash-form-body is set")
+		} else {
+			r.step("  computed", details.BodyHash)
+		}
+	}
+	r.step("Base string URI", details.BaseURL)
+	r.step("Parameter string", details.ParameterString)
+	r.step("Signature base string", details.SignatureBaseString)
+	signatureErr := signatureMethod.Verify(details.SignatureBaseString, details.Signature)
+	if signatureErr != nil {
+		r.step("Signature", "INVALID")
+	} else {
+		r.step("Signature", "ok")
+	}
+
+	switch {
+	case bodyHashErr != nil && signatureErr == nil:
+		return r.fail("body hash", fmt.Sprintf("%v, the body received differs from the body signed", bodyHashErr))
+	case bodyHashErr != nil:
+		return r.fail("body hash", bodyHashErr)
+	case signatureErr != nil:
+		return r.fail("signature", fmt.Sprintf("%v, check the method, the URL, the parameters and the certificate against the signature base string", signatureErr))
+	case err != nil:
+		return r.fail("verification", err)
+	}
+	fmt.Fprintln(r.w, "\nVERIFIED")
+	return true
+}
+
+// The newSignatureMethod returns the RSA signature method of the given
+// name, or nil.
+func newSignatureMethod(name string, publicKey *rsa.PublicKey) oauth.SignatureMethod {
+	switch name {
+	case oauth.RSASHA256MethodName:
+		return oauth.RSASHA256(nil, publicKey)
+	case oauth.RSASHA1MethodName:
+		return oauth.RSASHA1(nil, publicKey)
+	case oauth.RSASHA512MethodName:
+		return oauth.RSASHA512(nil, publicKey)
+	case oauth.RSAPSSSHA256MethodName:
+		return oauth.RSAPSSSHA256(nil, publicKey, 0)
+	}
+	return nil
+}
+
+// The formatTimestamp appends the time of an oauth_timestamp.
+func formatTimestamp(timestamp string) string {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return timestamp
+	}
+	return fmt.Sprintf("%v (%v)", timestamp, time.Unix(seconds, 0).UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"math/big"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The writeCertificate writes a self-signed certificate of the signing key.
+func writeCertificate(t *testing.T) string {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test Consumer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &signingKey.PublicKey, signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "consumer.crt")
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// The dumpSignedRequest returns the raw dump of a signed request.
+func dumpSignedRequest(rawURL, payload string) string {
+	req, _ := http.NewRequest("POST", rawURL, strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	_ = (&oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}).Sign(req)
+	dump, _ := httputil.DumpRequest(req, true)
+	return string(dump)
+}
+
+func TestRunVerify_ShouldReportFailedStep(t *testing.T) {
+
+	// GIVEN
+	cert := writeCertificate(t)
+	dump := dumpSignedRequest("https://api.example.com/service?a=1", `{"foo":"bar"}`)
+	proxied := strings.Replace(dumpSignedRequest("https://api.example.com/service", ""), "Host: api.example.com", "Host: internal:8080", 1)
+
+	tests := []struct {
+		name     string
+		args     []string
+		dump     string
+		code     int
+		expected string
+	}{
+		{"valid", nil, dump, 0, "VERIFIED"},
+		{"tampered body", nil, strings.Replace(dump, `"bar"`, `"baz"`, 1), 1, "FAILED at body hash: verifier: body hash mismatch, the body received differs from the body signed"},
+		{"tampered query", nil, strings.Replace(dump, "?a=1", "?a=2", 1), 1, "FAILED at signature: verifier: invalid signature"},
+		{"proxied", nil, proxied, 1, "FAILED at signature"},
+		{"external origin", []string{"-origin", "https://api.example.com"}, proxied, 0, "VERIFIED"},
+		{"unsigned", nil, "GET / HTTP/1.1\r\nHost: api.example.com\r\n\r\n", 1, "FAILED at Authorization header: verifier: missing authorization header"},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"verify", "-cert", cert}, test.args...)
+
+		// WHEN
+		code := run(append(args, "-"), strings.NewReader(test.dump), &stdout, &stderr)
+
+		// THEN
+		if code != test.code || !strings.Contains(stdout.String(), test.expected) {
+			t.Errorf("Expected %v and %v for the %v request, got %v %v%v", test.code, test.expected, test.name, code, stdout.String(), stderr.String())
+		}
+	}
+}
+
+func TestRunVerify_ShouldVerifyHAREntries(t *testing.T) {
+
+	// GIVEN
+	cert := writeCertificate(t)
+	req, _ := http.NewRequest("POST", "https://api.example.com/service", strings.NewReader("amount=10.00"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	signed, _ := (&oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}).SignWithDetails(req)
+	entry := map[string]interface{}{"request": map[string]interface{}{
+		"method":   "POST",
+		"url":      "https://api.example.com/service",
+		"headers":  []map[string]string{{"name": ":authority", "value": "api.example.com"}, {"name": "Authorization", "value": req.Header.Get("Authorization")}},
+		"postData": map[string]string{"mimeType": "application/x-www-form-urlencoded", "text": "amount=10.00"},
+	}}
+	har, _ := json.Marshal(map[string]interface{}{"log": map[string]interface{}{"entries": []interface{}{map[string]interface{}{}, entry}}})
+	path := filepath.Join(t.TempDir(), "capture.har")
+	_ = os.WriteFile(path, har, 0600)
+	var stdout, stderr bytes.Buffer
+
+	// WHEN
+	code := run([]string{"verify", "-cert", cert, "-entry", "1", path}, nil, &stdout, &stderr)
+
+	// THEN
+	if code != 0 || !strings.Contains(stdout.String(), "none, the form parameters are signed") {
+		t.Errorf("Expected the HAR entry to be verified, got %v %v%v", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), signed.SignatureBaseString) {
+		t.Errorf("Expected the signature base string, got %v", stdout.String())
+	}
+	if code = run([]string{"verify", "-cert", cert, "-entry", "2", path}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected an error for a missing entry, got %v", code)
+	}
+	if code = run([]string{"verify", path}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected a usage error without certificate, got %v", code)
+	}
+}
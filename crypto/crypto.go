@@ -3,22 +3,176 @@
 package crypto
 
 import (
+	"bytes"
+	"context"
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
+	_ "crypto/sha1" // registers SHA1
 	"crypto/sha256"
+	_ "crypto/sha512" // registers SHA512
+	"errors"
+	"fmt"
+	"io"
+	"sync"
 )
 
+// ContextSigner is a crypto.Signer that also accepts a context, which is
+// useful for keys held by remote services such as a HSM or a cloud KMS.
+// When a signer implements ContextSigner, SignContext is used in place
+// of Sign.
+type ContextSigner interface {
+	crypto.Signer
+	SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error)
+}
+
+// ErrUnsupportedHash is returned for hash algorithm names that have not
+// been registered.
+var ErrUnsupportedHash = errors.New("crypto: unsupported hash algorithm")
+
+var (
+	hashesMu sync.RWMutex
+	// hashes maps the algorithm names used in oauth_signature_method,
+	// such as the SHA256 of RSA-SHA256, to their hash function.
+	hashes = map[string]crypto.Hash{
+		"SHA1":   crypto.SHA1,
+		"SHA256": crypto.SHA256,
+		"SHA512": crypto.SHA512,
+	}
+)
+
+// RegisterHash makes a hash function available under the given algorithm
+// name. The hash function must be linked into the binary.
+func RegisterHash(name string, hash crypto.Hash) {
+	hashesMu.Lock()
+	defer hashesMu.Unlock()
+	hashes[name] = hash
+}
+
+// LookupHash returns the hash function registered under the given
+// algorithm name.
+func LookupHash(name string) (crypto.Hash, error) {
+	hashesMu.RLock()
+	hash, ok := hashes[name]
+	hashesMu.RUnlock()
+	if !ok || !hash.Available() {
+		return 0, fmt.Errorf("%w: %v", ErrUnsupportedHash, name)
+	}
+	return hash, nil
+}
+
+// Hash generates the hash of the provided data with the named algorithm.
+func Hash(name string, data []byte) ([]byte, error) {
+	return HashReader(name, bytes.NewReader(data))
+}
+
+// HashReader generates the hash of the data read from r until EOF with the
+// named algorithm. The data is hashed in chunks and never held in memory as
+// a whole.
+func HashReader(name string, r io.Reader) ([]byte, error) {
+	hash, err := LookupHash(name)
+	if err != nil {
+		return nil, err
+	}
+	h := hash.New()
+	if _, err = io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
 // Sha256 generates the SHA256 hash of the provided data
 func Sha256(data []byte) []byte {
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
 
-	hash := sha256.New()
-	hash.Write(data)
-	return hash.Sum(nil)
+// Sha256Reader generates the SHA256 hash of the data read from r until EOF.
+// The data is hashed in chunks and never held in memory as a whole.
+func Sha256Reader(r io.Reader) ([]byte, error) {
+	return HashReader("SHA256", r)
 }
 
 // Sign signs the given signing data by using the RSA PrivateKey.
 func Sign(data []byte, privateKey *rsa.PrivateKey) ([]byte, error) {
-	digest := sha256.Sum256(data)
-	return rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	return SignWithSigner(context.Background(), data, privateKey)
+}
+
+// SignWithSigner signs the given signing data by using a crypto.Signer
+// backed by an RSA key. The private key material never has to be loaded
+// in memory.
+func SignWithSigner(ctx context.Context, data []byte, signer crypto.Signer) ([]byte, error) {
+	return SignWithAlgorithm(ctx, data, signer, "SHA256")
+}
+
+// SignWithAlgorithm produces a PKCS#1 v1.5 signature of the data hashed with
+// the named algorithm, by using a crypto.Signer backed by an RSA key.
+func SignWithAlgorithm(ctx context.Context, data []byte, signer crypto.Signer, name string) ([]byte, error) {
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		return nil, errors.New("crypto: signer must hold an RSA key")
+	}
+	hash, err := LookupHash(name)
+	if err != nil {
+		return nil, err
+	}
+	h := hash.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+	if contextSigner, ok := signer.(ContextSigner); ok {
+		return contextSigner.SignContext(ctx, rand.Reader, digest, hash)
+	}
+	return signer.Sign(rand.Reader, digest, hash)
+}
+
+// Verify checks that the given signature is a valid RSA-SHA256 signature
+// of the data for the provided RSA PublicKey.
+func Verify(data, signature []byte, publicKey *rsa.PublicKey) error {
+	return VerifyWithAlgorithm(data, signature, publicKey, "SHA256")
+}
+
+// VerifyWithAlgorithm checks that the given signature is a valid PKCS#1 v1.5
+// signature of the data hashed with the named algorithm.
+func VerifyWithAlgorithm(data, signature []byte, publicKey *rsa.PublicKey, name string) error {
+	hash, err := LookupHash(name)
+	if err != nil {
+		return err
+	}
+	h := hash.New()
+	h.Write(data)
+	return rsa.VerifyPKCS1v15(publicKey, hash, h.Sum(nil), signature)
+}
+
+// SignPSSWithAlgorithm produces an RSASSA-PSS signature of the data hashed
+// with the named algorithm, by using a crypto.Signer backed by an RSA key.
+// The salt length follows rsa.PSSOptions, rsa.PSSSaltLengthEqualsHash being
+// the most interoperable choice.
+func SignPSSWithAlgorithm(ctx context.Context, data []byte, signer crypto.Signer, name string, saltLength int) ([]byte, error) {
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		return nil, errors.New("crypto: signer must hold an RSA key")
+	}
+	hash, err := LookupHash(name)
+	if err != nil {
+		return nil, err
+	}
+	h := hash.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+	opts := &rsa.PSSOptions{SaltLength: saltLength, Hash: hash}
+	if contextSigner, ok := signer.(ContextSigner); ok {
+		return contextSigner.SignContext(ctx, rand.Reader, digest, opts)
+	}
+	return signer.Sign(rand.Reader, digest, opts)
+}
+
+// VerifyPSSWithAlgorithm checks that the given signature is a valid
+// RSASSA-PSS signature of the data hashed with the named algorithm. With
+// rsa.PSSSaltLengthAuto, any salt length is accepted.
+func VerifyPSSWithAlgorithm(data, signature []byte, publicKey *rsa.PublicKey, name string, saltLength int) error {
+	hash, err := LookupHash(name)
+	if err != nil {
+		return err
+	}
+	h := hash.New()
+	h.Write(data)
+	return rsa.VerifyPSS(publicKey, hash, h.Sum(nil), signature, &rsa.PSSOptions{SaltLength: saltLength, Hash: hash})
 }
@@ -1,8 +1,17 @@
 package crypto_test
 
 import (
+	"bytes"
+	"context"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
 	"github.com/mastercard/oauth1-signer-go/crypto"
 	"github.com/mastercard/oauth1-signer-go/utils"
+	"io"
 	"testing"
 )
 
@@ -20,6 +29,65 @@ func TestSHA256Hash(t *testing.T) {
 	}
 }
 
+func TestSHA256HashOfReader(t *testing.T) {
+
+	input := bytes.Repeat([]byte("data"), 100000)
+	sha256, err := crypto.Sha256Reader(bytes.NewReader(input))
+
+	if err != nil || !bytes.Equal(sha256, crypto.Sha256(input)) {
+		t.Errorf("Expected the hash of the reader to match the hash of the data, got %v", err)
+	}
+}
+
+func TestHashRegistry(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		length int
+	}{
+		{"SHA1", 20},
+		{"SHA256", 32},
+		{"SHA512", 64},
+	}
+	for _, test := range tests {
+		hash, err := crypto.Hash(test.name, []byte("data"))
+		if err != nil || len(hash) != test.length {
+			t.Errorf("Expected a %v hash of %v bytes, got %v", test.name, test.length, err)
+		}
+	}
+
+	if _, err := crypto.Hash("SHA384", []byte("data")); !errors.Is(err, crypto.ErrUnsupportedHash) {
+		t.Errorf("Expected ErrUnsupportedHash, got %v", err)
+	}
+	t.Cleanup(crypto.SaveHashes())
+	crypto.RegisterHash("SHA384", gocrypto.SHA384)
+	if hash, err := crypto.Hash("SHA384", []byte("data")); err != nil || len(hash) != 48 {
+		t.Errorf("Expected the registered hash to be used, got %v", err)
+	}
+}
+
+func TestRSASignatureWithAlgorithm(t *testing.T) {
+
+	privateKey, _ := utils.LoadSigningKey("../testdata/test_key_container.p12", "Password1")
+	signingData := []byte("some data")
+	for _, name := range []string{"SHA1", "SHA256", "SHA512"} {
+		sign, err := crypto.SignWithAlgorithm(context.Background(), signingData, privateKey, name)
+		if err != nil {
+			t.Fatalf("Expected to generate a %v signature, but thrown %v", name, err)
+		}
+		if err = crypto.VerifyWithAlgorithm(signingData, sign, &privateKey.PublicKey, name); err != nil {
+			t.Errorf("Expected the %v signature to be valid, but thrown %v", name, err)
+		}
+	}
+	sign, _ := crypto.SignWithAlgorithm(context.Background(), signingData, privateKey, "SHA1")
+	if err := crypto.Verify(signingData, sign, &privateKey.PublicKey); err == nil {
+		t.Errorf("Expected a SHA1 signature not to be a valid SHA256 signature")
+	}
+	if _, err := crypto.SignWithAlgorithm(context.Background(), signingData, privateKey, "MD4"); !errors.Is(err, crypto.ErrUnsupportedHash) {
+		t.Errorf("Expected ErrUnsupportedHash, got %v", err)
+	}
+}
+
 func TestRSASignature(t *testing.T) {
 
 	privateKey, _ := utils.LoadSigningKey("../testdata/test_key_container.p12", "Password1")
@@ -30,3 +98,86 @@ func TestRSASignature(t *testing.T) {
 		t.Errorf("Expected to generate signature, but thrwon %v", err)
 	}
 }
+
+func TestRSAPSSSignature(t *testing.T) {
+
+	privateKey, _ := utils.LoadSigningKey("../testdata/test_key_container.p12", "Password1")
+	signingData := []byte("some data")
+
+	for _, saltLength := range []int{rsa.PSSSaltLengthEqualsHash, 20} {
+		sign, err := crypto.SignPSSWithAlgorithm(context.Background(), signingData, privateKey, "SHA256", saltLength)
+		if err != nil {
+			t.Fatalf("Expected to generate a PSS signature, but thrown %v", err)
+		}
+		if err = crypto.VerifyPSSWithAlgorithm(signingData, sign, &privateKey.PublicKey, "SHA256", saltLength); err != nil {
+			t.Errorf("Expected the PSS signature to be valid, but thrown %v", err)
+		}
+		if err = crypto.VerifyPSSWithAlgorithm(signingData, sign, &privateKey.PublicKey, "SHA256", rsa.PSSSaltLengthAuto); err != nil {
+			t.Errorf("Expected the PSS signature to be valid with any salt length, but thrown %v", err)
+		}
+		if err = crypto.Verify(signingData, sign, &privateKey.PublicKey); err == nil {
+			t.Errorf("Expected a PSS signature not to be a valid PKCS#1 v1.5 signature")
+		}
+	}
+
+	sign, _ := crypto.SignPSSWithAlgorithm(context.Background(), signingData, privateKey, "SHA256", 20)
+	if err := crypto.VerifyPSSWithAlgorithm(signingData, sign, &privateKey.PublicKey, "SHA256", 32); err == nil {
+		t.Errorf("Expected the PSS signature to be invalid with another salt length")
+	}
+}
+
+func TestRSASignatureVerification(t *testing.T) {
+
+	privateKey, _ := utils.LoadSigningKey("../testdata/test_key_container.p12", "Password1")
+	signingData := []byte("some data")
+	sign, _ := crypto.Sign(signingData, privateKey)
+
+	if err := crypto.Verify(signingData, sign, &privateKey.PublicKey); err != nil {
+		t.Errorf("Expected signature to be valid, but thrown %v", err)
+	}
+	if err := crypto.Verify([]byte("other data"), sign, &privateKey.PublicKey); err == nil {
+		t.Errorf("Expected an error in case of tampered data")
+	}
+}
+
+type contextKey struct{}
+
+type contextSigner struct {
+	gocrypto.Signer
+	ctx context.Context
+}
+
+func (s *contextSigner) SignContext(ctx context.Context, rand io.Reader, digest []byte, opts gocrypto.SignerOpts) ([]byte, error) {
+	s.ctx = ctx
+	return s.Signer.Sign(rand, digest, opts)
+}
+
+func TestSignWithSigner_ShouldUseContextSigner(t *testing.T) {
+
+	privateKey, _ := utils.LoadSigningKey("../testdata/test_key_container.p12", "Password1")
+	signer := &contextSigner{Signer: privateKey}
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	signingData := []byte("some data")
+
+	sign, err := crypto.SignWithSigner(ctx, signingData, signer)
+
+	if err != nil {
+		t.Errorf("Expected to generate signature, but thrown %v", err)
+	}
+	if signer.ctx != ctx {
+		t.Errorf("Expected SignContext to receive the context")
+	}
+	if err := crypto.Verify(signingData, sign, &privateKey.PublicKey); err != nil {
+		t.Errorf("Expected signature to be valid, but thrown %v", err)
+	}
+}
+
+func TestSignWithSigner_ShouldRejectNonRSAKeys(t *testing.T) {
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err := crypto.SignWithSigner(context.Background(), []byte("some data"), ecKey)
+
+	if err == nil {
+		t.Errorf("Expected an error in case of a non RSA key")
+	}
+}
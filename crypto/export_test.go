@@ -0,0 +1,19 @@
+package crypto
+
+import "crypto"
+
+// SaveHashes returns a function restoring the hash registry as it was, for
+// tests registering hashes.
+func SaveHashes() func() {
+	hashesMu.RLock()
+	saved := make(map[string]crypto.Hash, len(hashes))
+	for name, hash := range hashes {
+		saved[name] = hash
+	}
+	hashesMu.RUnlock()
+	return func() {
+		hashesMu.Lock()
+		hashes = saved
+		hashesMu.Unlock()
+	}
+}
@@ -0,0 +1,7 @@
+// Package pkcs11 provides RSA signing keys held in a PKCS#11 token, such as
+// a HSM. The private key material never leaves the token; the keys implement
+// crypto.Signer and can be used as oauth.Signer.Key.
+//
+// The package loads PKCS#11 modules through cgo and is only built when cgo
+// is enabled (the cgo build tag).
+package pkcs11
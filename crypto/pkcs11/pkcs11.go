@@ -0,0 +1,419 @@
+//go:build cgo
+
+package pkcs11
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	p11 "github.com/miekg/pkcs11"
+	"io"
+	"math/big"
+	"sync"
+)
+
+const (
+	defaultMaxSessions = 8
+)
+
+// ErrClosed is returned when signing with a closed key.
+var ErrClosed = errors.New("pkcs11: key closed")
+
+// digestInfoPrefixes holds the DER encoded DigestInfo prefixes the digest
+// must be wrapped in for CKM_RSA_PKCS, as per https://tools.ietf.org/html/rfc8017#section-9.2
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// modules holds the PKCS#11 modules loaded by New, by path. A module is
+// initialized once per process and shared by the keys loaded out of it.
+var (
+	modulesMu sync.Mutex
+	modules   = make(map[string]*module)
+)
+
+// The module is a loaded PKCS#11 module and the number of keys using it.
+type module struct {
+	path string
+	ctx  *p11.Ctx
+	refs int
+	// finalize is false when the module was initialized by another
+	// library of the process, which remains in charge of finalizing it
+	finalize bool
+}
+
+// The openModule loads and initializes the module at path, or returns the
+// module already loaded.
+func openModule(path string) (*module, error) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	if m, ok := modules[path]; ok {
+		m.refs++
+		return m, nil
+	}
+	ctx := p11.New(path)
+	if ctx == nil {
+		return nil, fmt.Errorf("pkcs11: unable to load module %v", path)
+	}
+	err := ctx.Initialize()
+	if err != nil && !isError(err, p11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		ctx.Destroy()
+		return nil, err
+	}
+	m := &module{path: path, ctx: ctx, refs: 1, finalize: err == nil}
+	modules[path] = m
+	return m, nil
+}
+
+// The release finalizes and unloads the module once no key uses it.
+func (m *module) release() error {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	if m.refs--; m.refs > 0 {
+		return nil
+	}
+	delete(modules, m.path)
+	var err error
+	if m.finalize {
+		err = m.ctx.Finalize()
+	}
+	m.ctx.Destroy()
+	return err
+}
+
+// pssParameters holds the hash and MGF1 mechanisms of CKM_RSA_PKCS_PSS
+// for each supported hash function.
+var pssParameters = map[crypto.Hash][2]uint{
+	crypto.SHA1:   {p11.CKM_SHA_1, p11.CKG_MGF1_SHA1},
+	crypto.SHA256: {p11.CKM_SHA256, p11.CKG_MGF1_SHA256},
+	crypto.SHA512: {p11.CKM_SHA512, p11.CKG_MGF1_SHA512},
+}
+
+// Config describes how to locate an RSA private key in a PKCS#11 token.
+type Config struct {
+	// Path is the path of the PKCS#11 module, for instance
+	// /usr/lib/softhsm/libsofthsm2.so.
+	Path string
+	// TokenLabel is the label of the token holding the key.
+	TokenLabel string
+	// PIN is the user PIN of the token.
+	PIN string
+	// KeyLabel and KeyID select the private key (CKA_LABEL and CKA_ID).
+	// At least one of them must be provided.
+	KeyLabel string
+	KeyID    []byte
+	// MaxSessions is the maximum number of sessions opened concurrently
+	// for signing. Defaults to 8.
+	MaxSessions int
+}
+
+// Key is an RSA private key held in a PKCS#11 token. It is safe for
+// concurrent use; signing operations are spread over a pool of sessions.
+type Key struct {
+	module    *module
+	ctx       *p11.Ctx
+	slot      uint
+	handle    p11.ObjectHandle
+	publicKey *rsa.PublicKey
+
+	// the login state is shared by all the sessions of the application
+	// and is kept for as long as one session remains open
+	loginSession p11.SessionHandle
+
+	// sessions holds idle sessions and slots limits the number of
+	// opened sessions
+	sessions chan p11.SessionHandle
+	slots    chan struct{}
+
+	// mu guards closed and inUse, the number of signing operations in
+	// progress, which keep the module loaded after Close
+	mu     sync.Mutex
+	closed bool
+	inUse  int
+}
+
+// New loads the PKCS#11 module, logs into the token and locates the
+// RSA private key described by the given configuration.
+func New(config Config) (*Key, error) {
+	if config.Path == "" {
+		return nil, errors.New("pkcs11: provide the PKCS#11 module path")
+	}
+	if config.KeyLabel == "" && len(config.KeyID) == 0 {
+		return nil, errors.New("pkcs11: provide the key label or the key ID")
+	}
+	maxSessions := config.MaxSessions
+	if maxSessions <= 0 {
+		maxSessions = defaultMaxSessions
+	}
+
+	m, err := openModule(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{
+		module:   m,
+		ctx:      m.ctx,
+		sessions: make(chan p11.SessionHandle, maxSessions),
+		slots:    make(chan struct{}, maxSessions),
+	}
+	if err = key.open(config); err != nil {
+		_ = key.Close()
+		return nil, err
+	}
+	return key, nil
+}
+
+// The open logs into the token and loads the key handle and public key.
+func (k *Key) open(config Config) error {
+	slot, err := findSlot(k.ctx, config.TokenLabel)
+	if err != nil {
+		return err
+	}
+	k.slot = slot
+	k.loginSession, err = k.ctx.OpenSession(slot, p11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return err
+	}
+	if err = k.ctx.Login(k.loginSession, p11.CKU_USER, config.PIN); err != nil && !isError(err, p11.CKR_USER_ALREADY_LOGGED_IN) {
+		return err
+	}
+
+	template := []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PRIVATE_KEY),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_RSA),
+	}
+	if config.KeyLabel != "" {
+		template = append(template, p11.NewAttribute(p11.CKA_LABEL, config.KeyLabel))
+	}
+	if len(config.KeyID) > 0 {
+		template = append(template, p11.NewAttribute(p11.CKA_ID, config.KeyID))
+	}
+	k.handle, err = findObject(k.ctx, k.loginSession, template)
+	if err != nil {
+		return err
+	}
+
+	attributes, err := k.ctx.GetAttributeValue(k.loginSession, k.handle, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_MODULUS, nil),
+		p11.NewAttribute(p11.CKA_PUBLIC_EXPONENT, nil),
+	})
+	if err != nil {
+		return err
+	}
+	k.publicKey = &rsa.PublicKey{
+		N: new(big.Int).SetBytes(attributes[0].Value),
+		E: int(new(big.Int).SetBytes(attributes[1].Value).Int64()),
+	}
+	return nil
+}
+
+// Public returns the RSA public key matching the private key held in the token.
+func (k *Key) Public() crypto.PublicKey {
+	return k.publicKey
+}
+
+// Sign signs the digest in the token using RSA PKCS#1 v1.5, or RSASSA-PSS
+// when opts is a *rsa.PSSOptions.
+func (k *Key) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return k.SignContext(context.Background(), rand, digest, opts)
+}
+
+// SignContext is like Sign but waits for an idle session no longer than
+// the context allows.
+func (k *Key) SignContext(ctx context.Context, _ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if len(digest) != opts.HashFunc().Size() {
+		return nil, errors.New("pkcs11: digest length does not match the hash function")
+	}
+	mechanism, data, err := k.mechanism(digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := k.acquireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := k.sign(session, mechanism, data)
+	k.releaseSession(session, err)
+	return signature, err
+}
+
+// The mechanism returns the signing mechanism matching opts along with the
+// data to sign: the digest wrapped in a DigestInfo for PKCS#1 v1.5, or the
+// digest itself for PSS.
+func (k *Key) mechanism(digest []byte, opts crypto.SignerOpts) (*p11.Mechanism, []byte, error) {
+	hash := opts.HashFunc()
+	if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
+		params, ok := pssParameters[hash]
+		if !ok {
+			return nil, nil, fmt.Errorf("pkcs11: unsupported hash function %v", hash)
+		}
+		saltLength := pssOpts.SaltLength
+		switch saltLength {
+		case rsa.PSSSaltLengthEqualsHash:
+			saltLength = hash.Size()
+		case rsa.PSSSaltLengthAuto:
+			saltLength = (k.publicKey.N.BitLen()-1+7)/8 - 2 - hash.Size()
+		}
+		if saltLength < 0 {
+			return nil, nil, errors.New("pkcs11: invalid PSS salt length")
+		}
+		pssParams := p11.NewPSSParams(params[0], params[1], uint(saltLength))
+		return p11.NewMechanism(p11.CKM_RSA_PKCS_PSS, pssParams), digest, nil
+	}
+	prefix, ok := digestInfoPrefixes[hash]
+	if !ok {
+		return nil, nil, fmt.Errorf("pkcs11: unsupported hash function %v", hash)
+	}
+	return p11.NewMechanism(p11.CKM_RSA_PKCS, nil), append(append([]byte{}, prefix...), digest...), nil
+}
+
+func (k *Key) sign(session p11.SessionHandle, mechanism *p11.Mechanism, data []byte) ([]byte, error) {
+	if err := k.ctx.SignInit(session, []*p11.Mechanism{mechanism}, k.handle); err != nil {
+		return nil, err
+	}
+	return k.ctx.Sign(session, data)
+}
+
+// Close closes the sessions of the key and unloads the PKCS#11 module once
+// no other key loaded out of it remains open. Signing operations in progress
+// complete first and close their session when they return. Signing with a
+// closed key returns ErrClosed.
+func (k *Key) Close() error {
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		return nil
+	}
+	k.closed = true
+	for len(k.sessions) > 0 {
+		_ = k.ctx.CloseSession(<-k.sessions)
+		<-k.slots
+	}
+	idle := k.inUse == 0
+	k.mu.Unlock()
+	if !idle {
+		return nil
+	}
+	return k.unload()
+}
+
+// The unload closes the login session and releases the module, once the
+// key is closed and no signing operation remains in progress.
+func (k *Key) unload() error {
+	// the sessions of other keys of the same slot are left open
+	if k.loginSession != 0 {
+		_ = k.ctx.CloseSession(k.loginSession)
+	}
+	return k.module.release()
+}
+
+// The acquireSession returns an idle session, opens a new one when the
+// pool is not full or waits for a session to be released. The module
+// stays loaded until the session is released.
+func (k *Key) acquireSession(ctx context.Context) (p11.SessionHandle, error) {
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		return 0, ErrClosed
+	}
+	k.inUse++
+	k.mu.Unlock()
+	session, err := k.waitSession(ctx)
+	if err != nil {
+		k.releaseSession(0, err)
+	}
+	return session, err
+}
+
+// The waitSession returns an idle session, opens a new one when the pool
+// is not full or waits for a session to be released.
+func (k *Key) waitSession(ctx context.Context) (p11.SessionHandle, error) {
+	select {
+	case session := <-k.sessions:
+		return session, nil
+	default:
+	}
+	select {
+	case session := <-k.sessions:
+		return session, nil
+	case k.slots <- struct{}{}:
+		session, err := k.ctx.OpenSession(k.slot, p11.CKF_SERIAL_SESSION)
+		if err != nil {
+			<-k.slots
+			return 0, err
+		}
+		return session, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// The releaseSession returns the session to the pool. Sessions that failed
+// because of a device or session error, or released after Close, are closed
+// rather than reused. A zero session is a session that could not be acquired.
+func (k *Key) releaseSession(session p11.SessionHandle, err error) {
+	k.mu.Lock()
+	k.inUse--
+	switch {
+	case session == 0:
+	case k.closed || err != nil && (isError(err, p11.CKR_SESSION_HANDLE_INVALID) || isError(err, p11.CKR_SESSION_CLOSED) ||
+		isError(err, p11.CKR_DEVICE_ERROR) || isError(err, p11.CKR_DEVICE_REMOVED)):
+		_ = k.ctx.CloseSession(session)
+		<-k.slots
+	default:
+		k.sessions <- session
+	}
+	unload := k.closed && k.inUse == 0
+	k.mu.Unlock()
+	if unload {
+		_ = k.unload()
+	}
+}
+
+// The findSlot returns the slot of the token with the given label.
+func findSlot(ctx *p11.Ctx, tokenLabel string) (uint, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, err
+	}
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, err
+		}
+		if info.Label == tokenLabel {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("pkcs11: token %q not found", tokenLabel)
+}
+
+// The findObject returns the only object matching the template.
+func findObject(ctx *p11.Ctx, session p11.SessionHandle, template []*p11.Attribute) (p11.ObjectHandle, error) {
+	if err := ctx.FindObjectsInit(session, template); err != nil {
+		return 0, err
+	}
+	objects, _, err := ctx.FindObjects(session, 2)
+	_ = ctx.FindObjectsFinal(session)
+	if err != nil {
+		return 0, err
+	}
+	switch len(objects) {
+	case 0:
+		return 0, errors.New("pkcs11: private key not found")
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, errors.New("pkcs11: more than one private key matches the label and ID")
+	}
+}
+
+func isError(err error, code uint) bool {
+	var p11Err p11.Error
+	return errors.As(err, &p11Err) && uint(p11Err) == code
+}
@@ -0,0 +1,331 @@
+//go:build cgo
+
+package pkcs11_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/crypto/pkcs11"
+	"github.com/mastercard/oauth1-signer-go/interceptor"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	p11 "github.com/miekg/pkcs11"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const (
+	tokenLabel  = "oauth1-signer-go"
+	userPIN     = "1234"
+	soPIN       = "123456"
+	keyLabel    = "signing-key"
+	consumerKey = "WFQHgP6gI01ZxbpqUzdWQ_GpDVrym3dhY6Q9X3PZe4ba3850!3b9f3d6593d04a0cbefadaf8bb3975fb0000000000000000"
+)
+
+var (
+	keyID         = []byte{0x01, 0x02}
+	signingKey, _ = utils.LoadSigningKey("../../testdata/test_key_container.p12", "Password1")
+)
+
+// The softHSMModule returns the path of the SoftHSM v2 module. The integration
+// tests are skipped when SoftHSM is not installed; SOFTHSM2_MODULE can be used
+// to point to a non standard location.
+func softHSMModule(t *testing.T) string {
+	candidates := []string{
+		os.Getenv("SOFTHSM2_MODULE"),
+		"/usr/lib/softhsm/libsofthsm2.so",
+		"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/local/lib/softhsm/libsofthsm2.so",
+		"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+	}
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	t.Skip("SoftHSM v2 is not installed")
+	return ""
+}
+
+// The initToken initialises a SoftHSM token in a temporary directory and
+// imports the test signing key in it.
+func initToken(t *testing.T, module string) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "softhsm2.conf")
+	content := fmt.Sprintf("directories.tokendir = %v\nobjectstore.backend = file\n", dir)
+	if err := os.WriteFile(conf, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOFTHSM2_CONF", conf)
+
+	ctx := p11.New(module)
+	defer ctx.Destroy()
+	if err := ctx.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ctx.Finalize() }()
+
+	slots, err := ctx.GetSlotList(false)
+	if err != nil || len(slots) == 0 {
+		t.Fatalf("Expected an uninitialised slot, got %v", err)
+	}
+	if err := ctx.InitToken(slots[0], soPIN, tokenLabel); err != nil {
+		t.Fatal(err)
+	}
+	// SoftHSM reassigns the slot of an initialised token
+	slots, _ = ctx.GetSlotList(true)
+	var slot uint
+	for _, s := range slots {
+		if info, _ := ctx.GetTokenInfo(s); info.Label == tokenLabel {
+			slot = s
+		}
+	}
+	session, err := ctx.OpenSession(slot, p11.CKF_SERIAL_SESSION|p11.CKF_RW_SESSION)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ctx.CloseSession(session) }()
+	if err := ctx.Login(session, p11.CKU_SO, soPIN); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.InitPIN(session, userPIN); err != nil {
+		t.Fatal(err)
+	}
+	_ = ctx.Logout(session)
+	if err := ctx.Login(session, p11.CKU_USER, userPIN); err != nil {
+		t.Fatal(err)
+	}
+
+	signingKey.Precompute()
+	_, err = ctx.CreateObject(session, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PRIVATE_KEY),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_RSA),
+		p11.NewAttribute(p11.CKA_TOKEN, true),
+		p11.NewAttribute(p11.CKA_PRIVATE, true),
+		p11.NewAttribute(p11.CKA_SENSITIVE, true),
+		p11.NewAttribute(p11.CKA_SIGN, true),
+		p11.NewAttribute(p11.CKA_LABEL, keyLabel),
+		p11.NewAttribute(p11.CKA_ID, keyID),
+		p11.NewAttribute(p11.CKA_MODULUS, signingKey.N.Bytes()),
+		p11.NewAttribute(p11.CKA_PUBLIC_EXPONENT, big.NewInt(int64(signingKey.E)).Bytes()),
+		p11.NewAttribute(p11.CKA_PRIVATE_EXPONENT, signingKey.D.Bytes()),
+		p11.NewAttribute(p11.CKA_PRIME_1, signingKey.Primes[0].Bytes()),
+		p11.NewAttribute(p11.CKA_PRIME_2, signingKey.Primes[1].Bytes()),
+		p11.NewAttribute(p11.CKA_EXPONENT_1, signingKey.Precomputed.Dp.Bytes()),
+		p11.NewAttribute(p11.CKA_EXPONENT_2, signingKey.Precomputed.Dq.Bytes()),
+		p11.NewAttribute(p11.CKA_COEFFICIENT, signingKey.Precomputed.Qinv.Bytes()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSoftHSMSigning(t *testing.T) {
+
+	// GIVEN
+	module := softHSMModule(t)
+	initToken(t, module)
+	key, err := pkcs11.New(pkcs11.Config{
+		Path:        module,
+		TokenLabel:  tokenLabel,
+		PIN:         userPIN,
+		KeyLabel:    keyLabel,
+		KeyID:       keyID,
+		MaxSessions: 2,
+	})
+	if err != nil {
+		t.Fatalf("Expected to locate the key in the token, got %v", err)
+	}
+	defer key.Close()
+	signer := &oauth.Signer{ConsumerKey: consumerKey, Key: key}
+	verifier := &oauth.Verifier{PublicKey: &signingKey.PublicKey}
+
+	// WHEN
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", bytes.NewBufferString("{}"))
+			if err := signer.Sign(req); err != nil {
+				errs <- err
+				return
+			}
+			errs <- verifier.Verify(req)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// THEN
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected the requests to be signed in the token, got %v", err)
+		}
+	}
+}
+
+func TestSoftHSMSigningWithSignatureMethods(t *testing.T) {
+
+	// GIVEN
+	module := softHSMModule(t)
+	initToken(t, module)
+	key, err := pkcs11.New(pkcs11.Config{Path: module, TokenLabel: tokenLabel, PIN: userPIN, KeyLabel: keyLabel})
+	if err != nil {
+		t.Fatalf("Expected to locate the key in the token, got %v", err)
+	}
+	defer key.Close()
+
+	methods := []oauth.SignatureMethod{
+		oauth.RSASHA1(nil, nil),
+		oauth.RSASHA512(nil, nil),
+		oauth.RSAPSSSHA256(nil, nil, 0),
+		oauth.RSAPSSSHA256(nil, nil, 20),
+	}
+	for _, method := range methods {
+		signer := &oauth.Signer{ConsumerKey: consumerKey, Key: key, Method: method}
+		verifier := &oauth.Verifier{PublicKey: &signingKey.PublicKey, Method: method}
+		req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", bytes.NewBufferString("{}"))
+
+		// WHEN
+		err := signer.Sign(req)
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected the request to be signed with %v, got %v", method.Name(), err)
+		}
+		if err = verifier.Verify(req); err != nil {
+			t.Errorf("Expected the %v signature to be verified, got %v", method.Name(), err)
+		}
+	}
+}
+
+func TestSoftHSMSigningWithInterceptor(t *testing.T) {
+
+	// GIVEN
+	module := softHSMModule(t)
+	initToken(t, module)
+	key, err := pkcs11.New(pkcs11.Config{Path: module, TokenLabel: tokenLabel, PIN: userPIN, KeyLabel: keyLabel})
+	if err != nil {
+		t.Fatalf("Expected to locate the key in the token, got %v", err)
+	}
+	defer key.Close()
+	verifier := &oauth.Verifier{PublicKey: &signingKey.PublicKey}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := verifier.Verify(r); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	// WHEN
+	client := interceptor.GetHttpClientWithKey(consumerKey, key)
+	res, err := client.Get(server.URL + "/service?a=b")
+
+	// THEN
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Errorf("Expected the request to be verified, got %v, %v", res, err)
+	}
+}
+
+func TestSoftHSMKeysSharingModule(t *testing.T) {
+
+	// GIVEN
+	module := softHSMModule(t)
+	initToken(t, module)
+	config := pkcs11.Config{Path: module, TokenLabel: tokenLabel, PIN: userPIN, KeyLabel: keyLabel}
+	first, err := pkcs11.New(config)
+	if err != nil {
+		t.Fatalf("Expected to locate the key in the token, got %v", err)
+	}
+	second, err := pkcs11.New(config)
+	if err != nil {
+		t.Fatalf("Expected to locate the key again, got %v", err)
+	}
+	defer second.Close()
+	signer := &oauth.Signer{ConsumerKey: consumerKey, Key: second}
+	verifier := &oauth.Verifier{PublicKey: &signingKey.PublicKey}
+	req, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service", nil)
+
+	// WHEN
+	closeErr := first.Close()
+	err = signer.Sign(req)
+
+	// THEN
+	if closeErr != nil {
+		t.Errorf("Expected the first key to be closed, got %v", closeErr)
+	}
+	if err != nil {
+		t.Fatalf("Expected the second key to keep signing after the first key is closed, got %v", err)
+	}
+	if err = verifier.Verify(req); err != nil {
+		t.Errorf("Expected the signature to be verified, got %v", err)
+	}
+}
+
+func TestSoftHSMCloseWhileSigning(t *testing.T) {
+
+	// GIVEN
+	module := softHSMModule(t)
+	initToken(t, module)
+	key, err := pkcs11.New(pkcs11.Config{Path: module, TokenLabel: tokenLabel, PIN: userPIN, KeyLabel: keyLabel, MaxSessions: 2})
+	if err != nil {
+		t.Fatalf("Expected to locate the key in the token, got %v", err)
+	}
+	signer := &oauth.Signer{ConsumerKey: consumerKey, Key: key}
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service", nil)
+			errs <- signer.Sign(req)
+		}()
+	}
+
+	// WHEN
+	closeErr := key.Close()
+	wg.Wait()
+	close(errs)
+	digest := sha256.Sum256([]byte("payload"))
+	_, err = key.Sign(nil, digest[:], crypto.SHA256)
+
+	// THEN
+	if closeErr != nil {
+		t.Errorf("Expected the key to be closed, got %v", closeErr)
+	}
+	for err := range errs {
+		if err != nil && !errors.Is(err, pkcs11.ErrClosed) {
+			t.Errorf("Expected the requests in progress to complete or ErrClosed, got %v", err)
+		}
+	}
+	if !errors.Is(err, pkcs11.ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestNewWithInvalidInput(t *testing.T) {
+
+	if _, err := pkcs11.New(pkcs11.Config{KeyLabel: keyLabel}); err == nil {
+		t.Errorf("Expected an error in case of missing module path")
+	}
+	if _, err := pkcs11.New(pkcs11.Config{Path: "/path/to/module.so"}); err == nil {
+		t.Errorf("Expected an error in case of missing key label and ID")
+	}
+	if _, err := pkcs11.New(pkcs11.Config{Path: "/path/to/module.so", KeyLabel: keyLabel}); err == nil {
+		t.Errorf("Expected an error in case of missing module")
+	}
+}
@@ -0,0 +1,20 @@
+package oauth
+
+// SignatureDetails holds the intermediate values of an OAuth signature, for
+// debugging signatures rejected by a server.
+type SignatureDetails struct {
+	// BodyHash is the oauth_body_hash, empty for form-encoded bodies whose
+	// parameters are signed instead.
+	BodyHash string
+	// BaseURL is the base string URI, as per
+	// https://tools.ietf.org/html/rfc5849#section-3.4.1.2
+	BaseURL string
+	// ParameterString is the normalized request parameters, as per
+	// https://tools.ietf.org/html/rfc5849#section-3.4.1.3.2
+	ParameterString string
+	// SignatureBaseString is the string signed, as per
+	// https://tools.ietf.org/html/rfc5849#section-3.4.1.1
+	SignatureBaseString string
+	// Signature is the oauth_signature, before percent encoding.
+	Signature string
+}
@@ -1,5 +1,12 @@
 module github.com/mastercard/oauth1-signer-go
 
-go 1.23
+go 1.23.0
 
-require golang.org/x/crypto v0.35.0
+require (
+	github.com/miekg/pkcs11 v1.1.2
+	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78
+	gopkg.in/yaml.v3 v3.0.1
+	software.sslmate.com/src/go-pkcs12 v0.5.0
+)
+
+require golang.org/x/crypto v0.35.0 // indirect
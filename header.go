@@ -0,0 +1,217 @@
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	realmParam = "realm"
+)
+
+var (
+	// ErrMalformedAuthorizationHeader is returned when the Authorization
+	// header is not a valid OAuth header as per https://tools.ietf.org/html/rfc5849#section-3.5.1
+	ErrMalformedAuthorizationHeader = errors.New("oauth: malformed authorization header")
+	// ErrDuplicateParameter is returned when a parameter appears more than
+	// once in the Authorization header.
+	ErrDuplicateParameter = errors.New("oauth: duplicate parameter")
+)
+
+// OAuthParams holds the parameters of an OAuth Authorization header. Values
+// are stored percent decoded.
+type OAuthParams struct {
+	realm  string
+	params map[string]string
+}
+
+// ParseAuthorizationHeader parses an OAuth Authorization header as per
+// https://tools.ietf.org/html/rfc5849#section-3.5.1. It is the inverse
+// of GetAuthorizationHeader.
+func ParseAuthorizationHeader(authHeader string) (*OAuthParams, error) {
+	scheme := strings.TrimSpace(authorizationPrefix)
+	if len(authHeader) < len(scheme) || !strings.EqualFold(authHeader[:len(scheme)], scheme) {
+		return nil, ErrMalformedAuthorizationHeader
+	}
+	rest := authHeader[len(scheme):]
+	if rest != "" && !isLinearWhitespace(rest[0]) {
+		return nil, ErrMalformedAuthorizationHeader
+	}
+
+	p := &OAuthParams{params: make(map[string]string)}
+	hasRealm := false
+	for {
+		// empty list elements are allowed as per https://tools.ietf.org/html/rfc2616#section-2.1
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			break
+		}
+
+		// parameter name
+		i := strings.IndexFunc(rest, func(r rune) bool { return r == '=' || r == ' ' || r == '\t' })
+		if i <= 0 {
+			return nil, ErrMalformedAuthorizationHeader
+		}
+		name := rest[:i]
+		rest = skipLinearWhitespace(rest[i:])
+		if rest == "" || rest[0] != '=' {
+			return nil, ErrMalformedAuthorizationHeader
+		}
+
+		// quoted parameter value
+		value, remaining, err := readQuotedString(skipLinearWhitespace(rest[1:]))
+		if err != nil {
+			return nil, err
+		}
+		rest = skipLinearWhitespace(remaining)
+		if rest != "" {
+			if rest[0] != ',' {
+				return nil, ErrMalformedAuthorizationHeader
+			}
+			rest = rest[1:]
+		}
+
+		// the realm is a plain quoted-string and is not percent encoded
+		if name == realmParam {
+			if hasRealm {
+				return nil, fmt.Errorf("%w: %v", ErrDuplicateParameter, name)
+			}
+			p.realm = value
+			hasRealm = true
+			continue
+		}
+		if name, err = url.PathUnescape(name); err != nil {
+			return nil, ErrMalformedAuthorizationHeader
+		}
+		if value, err = url.PathUnescape(value); err != nil {
+			return nil, ErrMalformedAuthorizationHeader
+		}
+		if _, ok := p.params[name]; ok {
+			return nil, fmt.Errorf("%w: %v", ErrDuplicateParameter, name)
+		}
+		p.params[name] = value
+	}
+	return p, nil
+}
+
+// Realm returns the realm parameter, if any.
+func (p *OAuthParams) Realm() string {
+	return p.realm
+}
+
+// Get returns the percent decoded value of the given parameter, or an
+// empty string when the parameter is absent.
+func (p *OAuthParams) Get(name string) string {
+	return p.params[name]
+}
+
+// Lookup returns the percent decoded value of the given parameter and
+// whether the parameter is present.
+func (p *OAuthParams) Lookup(name string) (string, bool) {
+	v, ok := p.params[name]
+	return v, ok
+}
+
+// ConsumerKey returns the oauth_consumer_key parameter.
+func (p *OAuthParams) ConsumerKey() string {
+	return p.params[oauthConsumerKeyParam]
+}
+
+// Nonce returns the oauth_nonce parameter.
+func (p *OAuthParams) Nonce() string {
+	return p.params[oauthNonceParam]
+}
+
+// Timestamp returns the oauth_timestamp parameter.
+func (p *OAuthParams) Timestamp() string {
+	return p.params[oauthTimestampParam]
+}
+
+// SignatureMethod returns the oauth_signature_method parameter.
+func (p *OAuthParams) SignatureMethod() string {
+	return p.params[oauthSignatureMethodParam]
+}
+
+// Signature returns the base64 encoded oauth_signature parameter.
+func (p *OAuthParams) Signature() string {
+	return p.params[oauthSignatureParam]
+}
+
+// Version returns the oauth_version parameter.
+func (p *OAuthParams) Version() string {
+	return p.params[oauthVersionParam]
+}
+
+// BodyHash returns the base64 encoded oauth_body_hash parameter.
+func (p *OAuthParams) BodyHash() string {
+	return p.params[oauthBodyHashParam]
+}
+
+// Token returns the oauth_token parameter.
+func (p *OAuthParams) Token() string {
+	return p.params[oauthTokenParam]
+}
+
+// Callback returns the oauth_callback parameter.
+func (p *OAuthParams) Callback() string {
+	return p.params[oauthCallbackParam]
+}
+
+// Verifier returns the oauth_verifier parameter.
+func (p *OAuthParams) Verifier() string {
+	return p.params[oauthVerifierParam]
+}
+
+// Map returns a copy of the parameters, excluding the realm.
+func (p *OAuthParams) Map() map[string]string {
+	params := make(map[string]string, len(p.params))
+	for k, v := range p.params {
+		params[k] = v
+	}
+	return params
+}
+
+// String returns the Authorization header for the parameters, with every
+// value percent encoded.
+func (p *OAuthParams) String() string {
+	oauthParams := make(map[string]string, len(p.params)+1)
+	for k, v := range p.params {
+		oauthParams[percentEncode(k)] = percentEncode(v)
+	}
+	if p.realm != "" {
+		oauthParams[realmParam] = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(p.realm)
+	}
+	return getAuthorizationString(oauthParams)
+}
+
+// The readQuotedString reads a quoted-string as per https://tools.ietf.org/html/rfc2616#section-2.2
+// and returns the unquoted value along with the remaining input.
+func readQuotedString(s string) (string, string, error) {
+	if s == "" || s[0] != '"' {
+		return "", "", ErrMalformedAuthorizationHeader
+	}
+	var value strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return value.String(), s[i+1:], nil
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", "", ErrMalformedAuthorizationHeader
+			}
+		}
+		value.WriteByte(s[i])
+	}
+	return "", "", ErrMalformedAuthorizationHeader
+}
+
+func skipLinearWhitespace(s string) string {
+	return strings.TrimLeft(s, " \t")
+}
+
+func isLinearWhitespace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
@@ -0,0 +1,136 @@
+package oauth_test
+
+import (
+	"errors"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAuthorizationHeader_ShouldSupportRfcExample(t *testing.T) {
+
+	// GIVEN
+	authHeader := "OAuth realm=\"Example\",\n" +
+		"\toauth_consumer_key=\"9djdj82h48djs9d2\",\n" +
+		"\toauth_token=\"kkk9d7dh3k39sjv7\",\n" +
+		"\toauth_signature_method=\"HMAC-SHA1\",\n" +
+		"\toauth_timestamp=\"137131201\",\n" +
+		"\toauth_nonce=\"7d8f3e4a\",\n" +
+		"\toauth_signature=\"djosJKDKJSD8743243%2Fjdk33klY%3D\""
+
+	// WHEN
+	params, err := oauth.ParseAuthorizationHeader(strings.ReplaceAll(authHeader, "\n", ""))
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the header to be parsed, got %v", err)
+	}
+	if v := params.Realm(); v != "Example" {
+		t.Errorf("Expected realm Example, got %v", v)
+	}
+	if v := params.ConsumerKey(); v != "9djdj82h48djs9d2" {
+		t.Errorf("Expected consumer key 9djdj82h48djs9d2, got %v", v)
+	}
+	if v := params.Get("oauth_token"); v != "kkk9d7dh3k39sjv7" {
+		t.Errorf("Expected token kkk9d7dh3k39sjv7, got %v", v)
+	}
+	if v := params.SignatureMethod(); v != "HMAC-SHA1" {
+		t.Errorf("Expected signature method HMAC-SHA1, got %v", v)
+	}
+	if v := params.Timestamp(); v != "137131201" {
+		t.Errorf("Expected timestamp 137131201, got %v", v)
+	}
+	if v := params.Nonce(); v != "7d8f3e4a" {
+		t.Errorf("Expected nonce 7d8f3e4a, got %v", v)
+	}
+	if v := params.Signature(); v != "djosJKDKJSD8743243/jdk33klY=" {
+		t.Errorf("Expected percent decoded signature, got %v", v)
+	}
+	if _, ok := params.Lookup(oauth.AuthorizationHeaderName); ok {
+		t.Errorf("Expected unknown parameter to be absent")
+	}
+	if _, ok := params.Map()["realm"]; ok {
+		t.Errorf("Expected realm to be excluded from the parameters")
+	}
+}
+
+func TestParseAuthorizationHeader_ShouldSupportWhitespaceVariants(t *testing.T) {
+	expected := map[string]string{"oauth_nonce": "a b", "oauth_version": "1.0"}
+	for _, authHeader := range []string{
+		"OAuth oauth_nonce=\"a%20b\",oauth_version=\"1.0\"",
+		"OAuth oauth_nonce=\"a%20b\", oauth_version=\"1.0\"",
+		"oauth   oauth_nonce = \"a%20b\" ,\toauth_version=\"1.0\" ",
+		"OAuth\toauth_nonce=\"a%20b\",,oauth_version=\"1.0\",",
+	} {
+		params, err := oauth.ParseAuthorizationHeader(authHeader)
+		if err != nil {
+			t.Errorf("Expected %q to be parsed, got %v", authHeader, err)
+			continue
+		}
+		if v := params.Map(); !reflect.DeepEqual(v, expected) {
+			t.Errorf("Expected %v for %q, got %v", expected, authHeader, v)
+		}
+	}
+}
+
+func TestParseAuthorizationHeader_ShouldUnescapeQuotedStrings(t *testing.T) {
+	params, err := oauth.ParseAuthorizationHeader("OAuth realm=\"a \\\"quoted\\\" realm\", oauth_nonce=\"n\"")
+	if err != nil {
+		t.Fatalf("Expected the header to be parsed, got %v", err)
+	}
+	if v := params.Realm(); v != "a \"quoted\" realm" {
+		t.Errorf("Expected unescaped realm, got %v", v)
+	}
+}
+
+func TestParseAuthorizationHeader_ShouldRejectInvalidHeaders(t *testing.T) {
+	tests := []struct {
+		authHeader string
+		expected   error
+	}{
+		{"Bearer token", oauth.ErrMalformedAuthorizationHeader},
+		{"OAuthoauth_nonce=\"n\"", oauth.ErrMalformedAuthorizationHeader},
+		{"OAuth oauth_nonce=n", oauth.ErrMalformedAuthorizationHeader},
+		{"OAuth oauth_nonce=\"n", oauth.ErrMalformedAuthorizationHeader},
+		{"OAuth oauth_nonce", oauth.ErrMalformedAuthorizationHeader},
+		{"OAuth oauth_nonce=\"n\" oauth_version=\"1.0\"", oauth.ErrMalformedAuthorizationHeader},
+		{"OAuth oauth_nonce=\"%zz\"", oauth.ErrMalformedAuthorizationHeader},
+		{"OAuth oauth_nonce=\"a\",oauth_nonce=\"b\"", oauth.ErrDuplicateParameter},
+		{"OAuth realm=\"a\",realm=\"b\"", oauth.ErrDuplicateParameter},
+	}
+	for _, test := range tests {
+		if _, err := oauth.ParseAuthorizationHeader(test.authHeader); !errors.Is(err, test.expected) {
+			t.Errorf("Expected %v for %q, got %v", test.expected, test.authHeader, err)
+		}
+	}
+}
+
+func TestParseAuthorizationHeader_ShouldRoundTrip(t *testing.T) {
+
+	// GIVEN
+	u, _ := url.Parse("https://sandbox.api.mastercard.com/service")
+	authHeader, _ := oauth.GetAuthorizationHeader(u, "POST", []byte("payload"), consumerKey, signingKey)
+	params, _ := oauth.ParseAuthorizationHeader(authHeader)
+
+	// WHEN
+	reparsed, err := oauth.ParseAuthorizationHeader(params.String())
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected String() to produce a valid header, got %v", err)
+	}
+	if !reflect.DeepEqual(params, reparsed) {
+		t.Errorf("Expected %v, got %v", params, reparsed)
+	}
+	if v := params.ConsumerKey(); v != consumerKey {
+		t.Errorf("Expected consumer key %v, got %v", consumerKey, v)
+	}
+	if v := params.BodyHash(); v != "I59Z7VXnN8dxR89VrQwbAwttfudIp0JpUvm4UtWpNeU=" {
+		t.Errorf("Expected body hash of the payload, got %v", v)
+	}
+	if v := params.Version(); v != "1.0" {
+		t.Errorf("Expected version 1.0, got %v", v)
+	}
+}
@@ -0,0 +1,158 @@
+package interceptor
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Decision describes how the Transport handled a request. It is reported
+// to the hook set with WithDecisionHook.
+type Decision struct {
+	URL *url.URL
+	// Redirect is true when the request follows a redirect response.
+	Redirect bool
+	// Retry is the number of the retry of a request rejected because of
+	// its nonce or its timestamp, zero for the first attempt.
+	Retry int
+	// Signed is true when the request was signed.
+	Signed bool
+	// PreviousKey is true when the request was signed again with the key
+	// in use before a rotation, after its signature was rejected.
+	PreviousKey bool
+	// Stripped is true when an Authorization header was removed from
+	// a request to a host that is not allowed.
+	Stripped bool
+	// Reason explains why the request was not signed.
+	Reason string
+}
+
+const (
+	reasonSchemeNotAllowed = "scheme not allowed"
+	reasonHostNotAllowed   = "host not allowed"
+	reasonSigningDisabled  = "signing disabled for host"
+)
+
+// The defaultPorts are the ports matched by host rules with a port when
+// the URL has none.
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// The hostMatcher reports whether a lower-cased host name and a port match
+// a rule.
+type hostMatcher func(hostname, port string) bool
+
+// The hostRule returns the hostMatcher of a host rule, see WithAllowedHosts.
+func hostRule(rule string) hostMatcher {
+	host, port := strings.ToLower(rule), ""
+	if strings.HasPrefix(host, "[") || strings.Count(host, ":") == 1 {
+		if h, p, err := net.SplitHostPort(host); err == nil {
+			host, port = h, p
+		}
+	}
+	host = strings.Trim(host, "[]")
+	matchesHost := func(hostname string) bool { return hostname == host }
+	if strings.HasPrefix(host, "*.") {
+		suffix := host[1:]
+		matchesHost = func(hostname string) bool {
+			return strings.HasSuffix(hostname, suffix) && len(hostname) > len(suffix)
+		}
+	}
+	return func(hostname, requestPort string) bool {
+		return matchesHost(hostname) && (port == "" || port == requestPort)
+	}
+}
+
+// WithAllowedHosts restricts signing to the given hosts. A host is either
+// an exact host name ("api.mastercard.com") or a wildcard matching any
+// subdomain ("*.mastercard.com", which does not match "mastercard.com"),
+// optionally followed by a port ("api.mastercard.com:8443"). Hosts are
+// matched case insensitively. A host without a port matches any port, and
+// a host with a port only matches requests to this port, the default port
+// of the scheme being used when the URL has none.
+// Requests to other hosts, including the ones reached through a redirect,
+// are sent unsigned and with any Authorization header removed. All hosts
+// are allowed unless WithAllowedHosts or WithAllowedHostPattern is used,
+// and no host is allowed by WithAllowedHosts without hosts.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(t *Transport) {
+		if t.allowedHosts == nil {
+			t.allowedHosts = []hostMatcher{}
+		}
+		for _, host := range hosts {
+			t.allowedHosts = append(t.allowedHosts, hostRule(host))
+		}
+	}
+}
+
+// WithAllowedHostPattern allows the hosts whose lower-cased name matches
+// the regular expression as a whole, as if it was written
+// `^(?:pattern)$`, whatever the port. See WithAllowedHosts.
+func WithAllowedHostPattern(pattern *regexp.Regexp) Option {
+	anchored := regexp.MustCompile(`^(?:` + pattern.String() + `)$`)
+	return func(t *Transport) {
+		t.allowedHosts = append(t.allowedHosts, func(hostname, _ string) bool {
+			return anchored.MatchString(hostname)
+		})
+	}
+}
+
+// WithAllowedSchemes restricts signing to the given URL schemes, such as
+// "https". Requests using other schemes are handled as requests to hosts
+// that are not allowed. All schemes are allowed by default.
+func WithAllowedSchemes(schemes ...string) Option {
+	return func(t *Transport) {
+		if t.allowedSchemes == nil {
+			t.allowedSchemes = make(map[string]bool)
+		}
+		for _, scheme := range schemes {
+			t.allowedSchemes[strings.ToLower(scheme)] = true
+		}
+	}
+}
+
+// WithDecisionHook sets a function called with the Decision made for every
+// request, for instance to log requests that were not signed.
+func WithDecisionHook(hook func(Decision)) Option {
+	return func(t *Transport) {
+		t.decisionHook = hook
+	}
+}
+
+// The allows checks the request against the scheme allow-list and the host
+// rules, and returns the reason the request is not allowed, if any.
+func (t *Transport) allows(req *http.Request) (bool, string) {
+	scheme := strings.ToLower(req.URL.Scheme)
+	if t.allowedSchemes != nil && !t.allowedSchemes[scheme] {
+		return false, reasonSchemeNotAllowed
+	}
+	hostname, port := strings.ToLower(req.URL.Hostname()), req.URL.Port()
+	if port == "" {
+		port = defaultPorts[scheme]
+	}
+	for _, matches := range t.deniedHosts {
+		if matches(hostname, port) {
+			return false, reasonSigningDisabled
+		}
+	}
+	if t.allowedHosts == nil {
+		return true, ""
+	}
+	for _, matches := range t.allowedHosts {
+		if matches(hostname, port) {
+			return true, ""
+		}
+	}
+	return false, reasonHostNotAllowed
+}
+
+// The report passes the decision to the decision hook, if any.
+func (t *Transport) report(req *http.Request, decision Decision) {
+	if t.decisionHook == nil {
+		return
+	}
+	decision.URL = req.URL
+	decision.Redirect = req.Response != nil
+	t.decisionHook(decision)
+}
@@ -0,0 +1,136 @@
+package interceptor_test
+
+import (
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/interceptor"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+// The redirectingBase answers requests to /redirect with a redirect to the
+// URL given in the "to" query parameter and records the other requests.
+func redirectingBase(received map[string]string) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/redirect" {
+			header := http.Header{"Location": {req.URL.Query().Get("to")}}
+			return &http.Response{StatusCode: http.StatusFound, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+		received[req.URL.Host] = req.Header.Get(oauth.AuthorizationHeaderName)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+}
+
+func TestWithAllowedHosts_ShouldOnlySignAllowedHosts(t *testing.T) {
+
+	tests := []struct {
+		opt      interceptor.Option
+		url      string
+		expected bool
+	}{
+		{interceptor.WithAllowedHosts("api.mastercard.com"), "https://api.mastercard.com/service", true},
+		{interceptor.WithAllowedHosts("api.mastercard.com"), "https://API.Mastercard.com:443/service", true},
+		{interceptor.WithAllowedHosts("api.mastercard.com"), "https://sandbox.api.mastercard.com/service", false},
+		{interceptor.WithAllowedHosts("*.mastercard.com"), "https://sandbox.api.mastercard.com/service", true},
+		{interceptor.WithAllowedHosts("*.mastercard.com"), "https://mastercard.com/service", false},
+		{interceptor.WithAllowedHosts("*.mastercard.com"), "https://evilmastercard.com/service", false},
+		{interceptor.WithAllowedHostPattern(regexp.MustCompile(`^(sandbox\.)?api\.mastercard\.com$`)), "https://sandbox.api.mastercard.com/service", true},
+		{interceptor.WithAllowedHostPattern(regexp.MustCompile(`^(sandbox\.)?api\.mastercard\.com$`)), "https://api.mastercard.com.evil.org/service", false},
+		{interceptor.WithAllowedHostPattern(regexp.MustCompile(`api\.mastercard\.com`)), "https://api.mastercard.com/service", true},
+		{interceptor.WithAllowedHostPattern(regexp.MustCompile(`api\.mastercard\.com`)), "https://api.mastercard.com.evil.com/service", false},
+		{interceptor.WithAllowedHostPattern(regexp.MustCompile(`api\.mastercard\.com`)), "https://evil-api.mastercard.com/service", false},
+		{interceptor.WithAllowedHosts("api.mastercard.com:8443"), "https://api.mastercard.com:8443/service", true},
+		{interceptor.WithAllowedHosts("api.mastercard.com:8443"), "https://api.mastercard.com/service", false},
+		{interceptor.WithAllowedHosts("API.mastercard.com:443"), "https://api.mastercard.com/service", true},
+		{interceptor.WithAllowedHosts("*.mastercard.com:443"), "http://api.mastercard.com/service", false},
+		{interceptor.WithAllowedHosts("[::1]:8443"), "https://[::1]:8443/service", true},
+		{interceptor.WithSigningDisabledFor("api.mastercard.com"), "https://api.mastercard.com:8443/service", false},
+		{interceptor.WithSigningDisabledFor("api.mastercard.com:8443"), "https://api.mastercard.com/service", true},
+		{interceptor.WithAllowedHosts(), "https://api.mastercard.com/service", false},
+		{interceptor.WithAllowedSchemes("https"), "http://api.mastercard.com/service", false},
+		{interceptor.WithAllowedSchemes("https"), "https://api.mastercard.com/service", true},
+	}
+	for _, test := range tests {
+		received := make(map[string]string)
+		var decision interceptor.Decision
+		hook := interceptor.WithDecisionHook(func(d interceptor.Decision) { decision = d })
+		client := interceptor.NewTransport(newTestSigner(), interceptor.WithBase(redirectingBase(received)), test.opt, hook).Client()
+
+		// WHEN
+		response, err := client.Get(test.url)
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected the request to succeed, but got %v", err)
+		}
+		if signed := received[response.Request.URL.Host] != ""; signed != test.expected || decision.Signed != test.expected {
+			t.Errorf("Expected %v to be signed=%v, got %v", test.url, test.expected, signed)
+		}
+		if !decision.Signed && decision.Reason == "" {
+			t.Errorf("Expected the reason of the decision to be reported")
+		}
+	}
+}
+
+func TestWithAllowedHosts_ShouldApplyRedirectPolicy(t *testing.T) {
+
+	// GIVEN
+	received := make(map[string]string)
+	var decisions []interceptor.Decision
+	client := interceptor.NewTransport(newTestSigner(),
+		interceptor.WithBase(redirectingBase(received)),
+		interceptor.WithAllowedHosts("*.mastercard.com"),
+		interceptor.WithDecisionHook(func(d interceptor.Decision) { decisions = append(decisions, d) }),
+	).Client()
+
+	// WHEN
+	_, err := client.Get("https://sandbox.api.mastercard.com/redirect?to=https://api.mastercard.com/service")
+	_, err2 := client.Get("https://sandbox.api.mastercard.com/redirect?to=https://third-party.org/service")
+
+	// THEN
+	if err != nil || err2 != nil {
+		t.Fatalf("Expected the requests to succeed, but got %v, %v", err, err2)
+	}
+	if received["api.mastercard.com"] == "" {
+		t.Errorf("Expected the request to be signed again after the redirect")
+	}
+	if auth, ok := received["third-party.org"]; !ok || auth != "" {
+		t.Errorf("Expected the redirect to a third party not to be signed, got %q", auth)
+	}
+	if len(decisions) != 4 || !decisions[1].Redirect || !decisions[1].Signed || !decisions[3].Redirect || decisions[3].Signed {
+		t.Errorf("Expected the redirect decisions to be reported, got %+v", decisions)
+	}
+}
+
+func TestWithAllowedHosts_ShouldStripAuthorization(t *testing.T) {
+
+	tests := []interceptor.Option{
+		interceptor.WithAllowedHosts("api.mastercard.com"),
+		interceptor.WithSigningDisabledFor("third-party.org"),
+	}
+	for _, opt := range tests {
+		received := make(map[string]string)
+		var decision interceptor.Decision
+		transport := interceptor.NewTransport(newTestSigner(),
+			interceptor.WithBase(redirectingBase(received)),
+			opt,
+			interceptor.WithDecisionHook(func(d interceptor.Decision) { decision = d }),
+		)
+		request, _ := http.NewRequest("GET", "https://third-party.org/service", nil)
+		request.Header.Set(oauth.AuthorizationHeaderName, "OAuth oauth_consumer_key=\"leaked\"")
+
+		// WHEN
+		_, err := transport.RoundTrip(request)
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected the request to succeed, but got %v", err)
+		}
+		if received["third-party.org"] != "" || !decision.Stripped || decision.Reason == "" {
+			t.Errorf("Expected the Authorization header to be removed, got %+v", decision)
+		}
+		if request.Header.Get(oauth.AuthorizationHeaderName) == "" {
+			t.Errorf("Expected the original request not to be modified")
+		}
+	}
+}
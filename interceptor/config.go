@@ -0,0 +1,121 @@
+package interceptor
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	envConsumerKey        = "CONSUMER_KEY"
+	envSigningKey         = "SIGNING_KEY"
+	envSigningKeyPassword = "SIGNING_KEY_PASSWORD"
+	envSigningKeyAlias    = "SIGNING_KEY_ALIAS"
+	envSecretsDir         = "SECRETS_DIR"
+	envFileSuffix         = "_FILE"
+	fileSecretPrefix      = "file://"
+)
+
+// Config holds the credentials resolved by FromEnv.
+type Config struct {
+	ConsumerKey string
+	SigningKey  *rsa.PrivateKey
+}
+
+// FromEnv resolves the consumer key and the signing key out of the
+// environment. With the "MC" prefix, the following variables are read:
+//
+//	MC_CONSUMER_KEY          the consumer key
+//	MC_SIGNING_KEY           the PKCS#12 or PEM signing key
+//	MC_SIGNING_KEY_PASSWORD  the password of the signing key, if any
+//	MC_SIGNING_KEY_ALIAS     the alias of the key in the PKCS#12 container, if any
+//
+// Values can be given inline, as "base64:<base64 value>" or as a
+// "file://<path>" reference. Each variable can also be provided through
+// a file named by the variable suffixed with _FILE (MC_SIGNING_KEY_FILE),
+// or through a file named after the lower-cased variable (signing_key)
+// in the directory given by MC_SECRETS_DIR, such as a mounted secret.
+func FromEnv(prefix string) (*Config, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	consumerKey, err := lookupSecret(prefix, envConsumerKey, true)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, err := lookupSecret(prefix, envSigningKey, true)
+	if err != nil {
+		return nil, err
+	}
+	password, err := lookupSecret(prefix, envSigningKeyPassword, false)
+	if err != nil {
+		return nil, err
+	}
+	alias, err := lookupSecret(prefix, envSigningKeyAlias, false)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if config.ConsumerKey, err = utils.ResolveSecretString(consumerKey); err != nil {
+		return nil, err
+	}
+	data, err := utils.ResolveSecret(signingKey)
+	if err != nil {
+		return nil, err
+	}
+	passwordValue, err := utils.ResolveSecretString(password)
+	if err != nil {
+		return nil, err
+	}
+	aliasValue, err := utils.ResolveSecretString(alias)
+	if err != nil {
+		return nil, err
+	}
+	if config.SigningKey, err = utils.LoadSigningKeyFromBytes(data, passwordValue, aliasValue); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Signer returns an oauth.Signer for the resolved credentials.
+func (c *Config) Signer() *oauth.Signer {
+	return &oauth.Signer{ConsumerKey: c.ConsumerKey, SigningKey: c.SigningKey}
+}
+
+// HttpClient returns an http.Client signing every request with the
+// resolved credentials.
+func (c *Config) HttpClient(opts ...Option) *http.Client {
+	return NewTransport(c.Signer(), opts...).Client()
+}
+
+// The lookupSecret returns the secret reference of a variable out of the
+// environment, a _FILE variable or the secrets directory, in that order.
+// Files are returned as "file://" references, resolved with ResolveSecret.
+func lookupSecret(prefix, name string, required bool) (string, error) {
+	if ref, ok := os.LookupEnv(prefix + name); ok {
+		return ref, nil
+	}
+	if path, ok := os.LookupEnv(prefix + name + envFileSuffix); ok {
+		return fileSecretPrefix + path, nil
+	}
+	if dir, ok := os.LookupEnv(prefix + envSecretsDir); ok {
+		path := filepath.Join(dir, strings.ToLower(name))
+		_, err := os.Stat(path)
+		if err == nil {
+			return fileSecretPrefix + path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	if required {
+		return "", fmt.Errorf("interceptor: %v%v is not set", prefix, name)
+	}
+	return "", nil
+}
@@ -0,0 +1,114 @@
+package interceptor_test
+
+import (
+	"encoding/base64"
+	"github.com/mastercard/oauth1-signer-go/interceptor"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFromEnv_ShouldResolveFileReferences(t *testing.T) {
+
+	// GIVEN
+	t.Setenv("MC_CONSUMER_KEY", consumerKey)
+	t.Setenv("MC_SIGNING_KEY", "file://"+path)
+	t.Setenv("MC_SIGNING_KEY_PASSWORD", password)
+
+	// WHEN
+	config, err := interceptor.FromEnv("MC")
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the configuration to be resolved, got %v", err)
+	}
+	if config.ConsumerKey != consumerKey || config.SigningKey == nil {
+		t.Errorf("Expected the consumer key and the signing key, got %v", config)
+	}
+	if signer := config.Signer(); signer.ConsumerKey != consumerKey || signer.SigningKey != config.SigningKey {
+		t.Errorf("Expected a signer using the resolved credentials")
+	}
+	if client := config.HttpClient(); client == nil {
+		t.Errorf("Expected http.Client")
+	}
+}
+
+func TestFromEnv_ShouldResolveInlineValues(t *testing.T) {
+
+	// GIVEN
+	data, _ := os.ReadFile(path)
+	pem, _ := os.ReadFile("../testdata/test_key_pkcs8.pem")
+
+	for _, signingKey := range []string{"base64:" + base64.StdEncoding.EncodeToString(data), string(pem)} {
+		t.Setenv("MC_CONSUMER_KEY", consumerKey)
+		t.Setenv("MC_SIGNING_KEY", signingKey)
+		t.Setenv("MC_SIGNING_KEY_PASSWORD", password)
+
+		// WHEN
+		config, err := interceptor.FromEnv("MC_")
+
+		// THEN
+		if err != nil || config.SigningKey == nil {
+			t.Errorf("Expected the signing key to be resolved, got %v", err)
+		}
+	}
+}
+
+func TestFromEnv_ShouldResolveSecretFiles(t *testing.T) {
+
+	// GIVEN
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "consumer_key"), []byte(consumerKey+"\n"), 0600)
+	_ = os.WriteFile(filepath.Join(dir, "password"), []byte(password+"\n"), 0600)
+	t.Setenv("MC_SECRETS_DIR", dir)
+	t.Setenv("MC_SIGNING_KEY_FILE", path)
+	t.Setenv("MC_SIGNING_KEY_PASSWORD_FILE", filepath.Join(dir, "password"))
+
+	// WHEN
+	config, err := interceptor.FromEnv("MC")
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the configuration to be resolved, got %v", err)
+	}
+	if config.ConsumerKey != consumerKey {
+		t.Errorf("Expected the consumer key without line break, got %q", config.ConsumerKey)
+	}
+}
+
+func TestFromEnv_ShouldSelectKeyByAlias(t *testing.T) {
+
+	// GIVEN
+	expected, _ := utils.LoadSigningKey("../testdata/test_key_container.p12", "Password1")
+	t.Setenv("MC_CONSUMER_KEY", consumerKey)
+	t.Setenv("MC_SIGNING_KEY", "file://../testdata/test_key_container_multi.p12")
+	t.Setenv("MC_SIGNING_KEY_PASSWORD", "Password1")
+	t.Setenv("MC_SIGNING_KEY_ALIAS", "first")
+
+	// WHEN
+	config, err := interceptor.FromEnv("MC")
+
+	// THEN
+	if err != nil || !config.SigningKey.Equal(expected) {
+		t.Errorf("Expected the key of the alias to be resolved, got %v", err)
+	}
+}
+
+func TestFromEnvInvalidInput(t *testing.T) {
+
+	t.Setenv("MC_SIGNING_KEY", "file://"+path)
+	if _, err := interceptor.FromEnv("MC"); err == nil {
+		t.Errorf("Expected an error in case of missing consumer key")
+	}
+
+	t.Setenv("MC_CONSUMER_KEY", consumerKey)
+	if _, err := interceptor.FromEnv("MC"); err == nil {
+		t.Errorf("Expected an error in case of missing password")
+	}
+
+	t.Setenv("MC_SIGNING_KEY", "file://../testdata/invalidFile.p12")
+	if _, err := interceptor.FromEnv("MC"); err == nil {
+		t.Errorf("Expected an error in case of missing key file")
+	}
+}
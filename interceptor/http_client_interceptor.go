@@ -3,30 +3,12 @@
 package interceptor
 
 import (
+	"crypto"
 	"github.com/mastercard/oauth1-signer-go"
 	"github.com/mastercard/oauth1-signer-go/utils"
 	"net/http"
 )
 
-// The httpClientInterceptor is the composition of http.RoundTripper and oauth.Signer
-// Every http call can be intercepted through http.RoundTripper and
-// oauth.Signer is used to sign the http request and generate oauth1.0a
-// authentication header
-type httpClientInterceptor struct {
-	http.RoundTripper
-	oauth.Signer
-}
-
-// RoundTrip intercepts every http call and signs the http request
-// before making an actual call
-func (h *httpClientInterceptor) RoundTrip(req *http.Request) (*http.Response, error) {
-	err := h.Signer.Sign(req)
-	if err != nil {
-		return nil, err
-	}
-	return h.RoundTripper.RoundTrip(req)
-}
-
 // GetHttpClient provides the http.Client having capability to intercept
 // the http call and add the generated oauth1.0a header in each request.
 // consumerKey: provide the consumer key received from mastercard developer portal
@@ -37,12 +19,27 @@ func GetHttpClient(consumerKey, filePath, password string) (*http.Client, error)
 	if e != nil {
 		return nil, e
 	}
-	signer := oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}
+
+	return NewTransport(signer).Client(), nil
+}
+
+// GetHttpClientWithKey provides the http.Client signing every request with
+// a crypto.Signer backed by an RSA key, such as a key held in a HSM.
+// consumerKey: provide the consumer key received from mastercard developer portal
+// key: a crypto.Signer holding the RSA private key
+func GetHttpClientWithKey(consumerKey string, key crypto.Signer) *http.Client {
+	signer := &oauth.Signer{ConsumerKey: consumerKey, Key: key}
+
+	return NewTransport(signer).Client()
+}
+
+// GetHttpClientWithProvider provides the http.Client signing every request
+// with the credentials currently held by the provider, such as a
+// utils.KeyProvider reloading rotated keys.
+// provider: supplies the consumer key and the signing key of every request
+func GetHttpClientWithProvider(provider oauth.CredentialsProvider) *http.Client {
+	signer := &oauth.Signer{Credentials: provider}
 
-	return &http.Client{
-		Transport: &httpClientInterceptor{
-			http.DefaultTransport,
-			signer,
-		},
-	}, nil
+	return NewTransport(signer).Client()
 }
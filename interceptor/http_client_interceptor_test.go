@@ -1,8 +1,12 @@
 package interceptor_test
 
 import (
+	oauth "github.com/mastercard/oauth1-signer-go"
 	"github.com/mastercard/oauth1-signer-go/interceptor"
+	"github.com/mastercard/oauth1-signer-go/utils"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -41,3 +45,53 @@ func TestRoundTripWithInvalidInput(t *testing.T) {
 		t.Errorf("Expected an error to be thrown in case of invalid consumer key")
 	}
 }
+
+func TestHttpClientInterceptorWithKey(t *testing.T) {
+
+	// GIVEN
+	signingKey, _ := utils.LoadSigningKey(path, password)
+	var authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get(oauth.AuthorizationHeaderName)
+	}))
+	defer server.Close()
+
+	// WHEN
+	client := interceptor.GetHttpClientWithKey(consumerKey, signingKey)
+	_, e := client.Get(server.URL + "/service")
+
+	// THEN
+	if e != nil {
+		t.Errorf("Expected the request to succeed, but got %v", e)
+	}
+	if !strings.HasPrefix(authHeader, "OAuth ") {
+		t.Errorf("Expected the authorization header, got %v", authHeader)
+	}
+}
+
+func TestHttpClientInterceptorWithProvider(t *testing.T) {
+
+	// GIVEN
+	provider, _ := utils.NewKeyProvider(utils.KeyProviderConfig{
+		Load:         utils.FileKeyLoader(consumerKey, path, password, ""),
+		PollInterval: -1,
+	})
+	defer provider.Close()
+	var authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get(oauth.AuthorizationHeaderName)
+	}))
+	defer server.Close()
+
+	// WHEN
+	client := interceptor.GetHttpClientWithProvider(provider)
+	_, e := client.Get(server.URL + "/service")
+
+	// THEN
+	if e != nil {
+		t.Errorf("Expected the request to succeed, but got %v", e)
+	}
+	if params, err := oauth.ParseAuthorizationHeader(authHeader); err != nil || params.ConsumerKey() != consumerKey {
+		t.Errorf("Expected the authorization header, got %v", authHeader)
+	}
+}
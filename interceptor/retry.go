@@ -0,0 +1,184 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/rsa"
+	"github.com/mastercard/oauth1-signer-go"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	nonceUsed           = "nonce_used"
+	signatureInvalid    = "signature_invalid"
+	consumerKeyUnknown  = "consumer_key_unknown"
+	consumerKeyRejected = "consumer_key_rejected"
+
+	defaultMaxRetries = 1
+	defaultBackoff    = 100 * time.Millisecond
+	maxDrainedBytes   = 4096
+)
+
+// RetryPolicy configures the retry of signed requests rejected because of
+// their nonce or their timestamp. Retried requests are signed again, hence
+// carry a new nonce and timestamp.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt, 1 by
+	// default.
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubled before every
+	// other retry. It defaults to 100ms.
+	Backoff time.Duration
+	// Methods lists the methods retried in addition to the idempotent
+	// methods of https://tools.ietf.org/html/rfc7231#section-4.2.2, for
+	// instance "POST" for endpoints deduplicating requests.
+	Methods []string
+	// ShouldRetry reports whether the response rejects the nonce or the
+	// timestamp. By default, 401 responses whose WWW-Authenticate header
+	// has the oauth_problem "nonce_used" or "timestamp_refused" are retried.
+	ShouldRetry func(res *http.Response) bool
+}
+
+// WithRetryPolicy retries the signed requests rejected because of their
+// nonce or their timestamp. Only requests whose body can be rewound, that
+// is without body or with a GetBody function, are retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(t *Transport) {
+		t.retry = &policy
+	}
+}
+
+// The retries reports whether the response to the request should be
+// retried after the given number of retries.
+func (p *RetryPolicy) retries(req *http.Request, res *http.Response, retry int) bool {
+	maxRetries := p.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+	if retry >= maxRetries || !p.allowsMethod(req.Method) || !rewindable(req) {
+		return false
+	}
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(res)
+	}
+	return isReplayRejection(res)
+}
+
+// The allowsMethod reports whether requests of the given method can be
+// sent again.
+func (p *RetryPolicy) allowsMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	for _, m := range p.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// The wait sleeps before the given retry, unless the context is done.
+func (p *RetryPolicy) wait(ctx context.Context, retry int) error {
+	backoff := p.Backoff
+	if backoff == 0 {
+		backoff = defaultBackoff
+	}
+	timer := time.NewTimer(backoff << (retry - 1))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// The previousCredentials is implemented by credentials providers keeping
+// the credentials in use before a rotation, such as utils.KeyProvider.
+type previousCredentials interface {
+	Previous() (consumerKey string, signingKey *rsa.PrivateKey, ok bool)
+}
+
+// The retryWithPreviousKey signs the request again with the previous
+// credentials of the signer, if any, when the response rejects its
+// signature or its consumer key, for instance while a rotated key has not
+// propagated to the servers yet.
+func (t *Transport) retryWithPreviousKey(req *http.Request, res *http.Response) (*http.Response, error) {
+	provider, ok := t.signer.Credentials.(previousCredentials)
+	if !ok || !rewindable(req) || !isKeyRejection(res) {
+		return res, nil
+	}
+	consumerKey, signingKey, ok := provider.Previous()
+	if !ok {
+		return res, nil
+	}
+	rewound, err := rewind(req)
+	if err != nil {
+		return nil, err
+	}
+	discard(res)
+	previous := *t.signer
+	previous.Credentials, previous.Key = nil, nil
+	previous.ConsumerKey, previous.SigningKey = consumerKey, signingKey
+	return t.signAndSend(&previous, rewound, Decision{PreviousKey: true})
+}
+
+// The isKeyRejection reports whether the response rejects the signature or
+// the consumer key of the request.
+func isKeyRejection(res *http.Response) bool {
+	if res.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	params, err := oauth.ParseAuthorizationHeader(res.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		return false
+	}
+	switch params.Get(oauthProblemParam) {
+	case signatureInvalid, consumerKeyUnknown, consumerKeyRejected:
+		return true
+	}
+	return false
+}
+
+// The isReplayRejection reports whether the response refuses the nonce or
+// the timestamp as per https://wiki.oauth.net/w/page/12238543/ProblemReporting
+func isReplayRejection(res *http.Response) bool {
+	if res.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	params, err := oauth.ParseAuthorizationHeader(res.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		return false
+	}
+	problem := params.Get(oauthProblemParam)
+	return problem == nonceUsed || problem == timestampRefused
+}
+
+// The rewindable reports whether the body of the request can be sent again.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// The rewind returns a copy of the request with a fresh body.
+func rewind(req *http.Request) (*http.Request, error) {
+	rewound := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		rewound.Body = body
+	}
+	return rewound, nil
+}
+
+// The discard drains and closes the body of a response that is not
+// returned, so that the connection can be reused.
+func discard(res *http.Response) {
+	_, _ = io.CopyN(io.Discard, res.Body, maxDrainedBytes)
+	_ = res.Body.Close()
+}
@@ -0,0 +1,200 @@
+package interceptor_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/interceptor"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The rejectingBase rejects the nonce of the first requests and records the
+// nonces and bodies received.
+func rejectingBase(rejections int, nonces, bodies *[]string) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		params, _ := oauth.ParseAuthorizationHeader(req.Header.Get(oauth.AuthorizationHeaderName))
+		*nonces = append(*nonces, params.Nonce())
+		if req.Body != nil {
+			body, _ := io.ReadAll(req.Body)
+			*bodies = append(*bodies, string(body))
+		}
+		if len(*nonces) <= rejections {
+			header := http.Header{"Www-Authenticate": {"OAuth oauth_problem=\"nonce_used\""}}
+			return &http.Response{StatusCode: http.StatusUnauthorized, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+}
+
+func TestWithRetryPolicy_ShouldSignAgainAndRetry(t *testing.T) {
+
+	// GIVEN
+	var nonces, bodies []string
+	var decisions []interceptor.Decision
+	client := interceptor.NewTransport(newTestSigner(),
+		interceptor.WithBase(rejectingBase(2, &nonces, &bodies)),
+		interceptor.WithRetryPolicy(interceptor.RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}),
+		interceptor.WithDecisionHook(func(d interceptor.Decision) { decisions = append(decisions, d) }),
+	).Client()
+	request, _ := http.NewRequest("PUT", "https://sandbox.api.mastercard.com/service", strings.NewReader("payload"))
+
+	// WHEN
+	response, err := client.Do(request)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the request to succeed, but got %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected the request to be retried until accepted, got %v", response.StatusCode)
+	}
+	if len(nonces) != 3 || nonces[0] == nonces[1] || nonces[1] == nonces[2] {
+		t.Errorf("Expected a new nonce for every attempt, got %v", nonces)
+	}
+	if len(bodies) != 3 || bodies[2] != "payload" {
+		t.Errorf("Expected the body to be rewound, got %v", bodies)
+	}
+	if len(decisions) != 3 || decisions[2].Retry != 2 {
+		t.Errorf("Expected the retries to be reported, got %+v", decisions)
+	}
+}
+
+func TestWithRetryPolicy_ShouldOnlyRetryAllowedMethods(t *testing.T) {
+
+	tests := []struct {
+		policy   interceptor.RetryPolicy
+		method   string
+		attempts int
+	}{
+		{interceptor.RetryPolicy{Backoff: time.Millisecond}, "POST", 1},
+		{interceptor.RetryPolicy{Backoff: time.Millisecond, Methods: []string{"post"}}, "POST", 2},
+		{interceptor.RetryPolicy{Backoff: time.Millisecond}, "GET", 2},
+		{interceptor.RetryPolicy{Backoff: time.Millisecond, ShouldRetry: func(*http.Response) bool { return false }}, "GET", 1},
+	}
+	for _, test := range tests {
+		var nonces, bodies []string
+		client := interceptor.NewTransport(newTestSigner(),
+			interceptor.WithBase(rejectingBase(5, &nonces, &bodies)),
+			interceptor.WithRetryPolicy(test.policy)).Client()
+		request, _ := http.NewRequest(test.method, "https://sandbox.api.mastercard.com/service", strings.NewReader("payload"))
+
+		// WHEN
+		response, err := client.Do(request)
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected the request to succeed, but got %v", err)
+		}
+		if len(nonces) != test.attempts || response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected %v attempts for %v, got %v", test.attempts, test.method, len(nonces))
+		}
+	}
+}
+
+func TestWithRetryPolicy_ShouldNotRetryOneShotBodies(t *testing.T) {
+
+	// GIVEN
+	var nonces, bodies []string
+	transport := interceptor.NewTransport(newTestSigner(),
+		interceptor.WithBase(rejectingBase(1, &nonces, &bodies)),
+		interceptor.WithRetryPolicy(interceptor.RetryPolicy{Backoff: time.Millisecond}))
+	request, _ := http.NewRequest("PUT", "https://sandbox.api.mastercard.com/service", nil)
+	request.Body = io.NopCloser(strings.NewReader("payload"))
+
+	// WHEN
+	response, err := transport.RoundTrip(request)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the request to succeed, but got %v", err)
+	}
+	if len(nonces) != 1 || response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the request not to be retried, got %v attempts", len(nonces))
+	}
+}
+
+func TestWithRetryPolicy_ShouldStopWhenContextDone(t *testing.T) {
+
+	// GIVEN
+	var nonces, bodies []string
+	transport := interceptor.NewTransport(newTestSigner(),
+		interceptor.WithBase(rejectingBase(1, &nonces, &bodies)),
+		interceptor.WithRetryPolicy(interceptor.RetryPolicy{Backoff: time.Hour}))
+	ctx, cancel := context.WithCancel(context.Background())
+	request, _ := http.NewRequestWithContext(ctx, "GET", "https://sandbox.api.mastercard.com/service", nil)
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	// WHEN
+	_, err := transport.RoundTrip(request)
+
+	// THEN
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the backoff to be interrupted, got %v", err)
+	}
+}
+
+func TestTransport_ShouldSignAgainWithPreviousKey(t *testing.T) {
+
+	// GIVEN
+	original, _ := utils.LoadSigningKey(path, password)
+	rotated, _ := utils.LoadSigningKeyFromPEMFile("../testdata/test_key_rotated.pem", "")
+	signingKey := original
+	provider, _ := utils.NewKeyProvider(utils.KeyProviderConfig{
+		Load:         func() (string, *rsa.PrivateKey, error) { return consumerKey, signingKey, nil },
+		PollInterval: -1,
+		Overlap:      time.Minute,
+	})
+	defer provider.Close()
+	signingKey = rotated
+	_ = provider.Reload()
+
+	// the server only knows the original key
+	verifier := &oauth.Verifier{PublicKey: &original.PublicKey}
+	var decisions []interceptor.Decision
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := verifier.Verify(req); err != nil {
+			header := http.Header{"Www-Authenticate": {"OAuth oauth_problem=\"signature_invalid\""}}
+			return &http.Response{StatusCode: http.StatusUnauthorized, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	client := interceptor.NewTransport(&oauth.Signer{Credentials: provider},
+		interceptor.WithBase(base),
+		interceptor.WithDecisionHook(func(d interceptor.Decision) { decisions = append(decisions, d) }),
+	).Client()
+	request, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", strings.NewReader("payload"))
+
+	// WHEN
+	response, err := client.Do(request)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the request to succeed, but got %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected the request to be signed again with the previous key, got %v", response.StatusCode)
+	}
+	if len(decisions) != 2 || decisions[0].PreviousKey || !decisions[1].PreviousKey {
+		t.Errorf("Expected the second attempt to be reported, got %+v", decisions)
+	}
+
+	// without previous key, the rejection is returned
+	decisions = nil
+	client = interceptor.NewTransport(&oauth.Signer{ConsumerKey: consumerKey, SigningKey: rotated},
+		interceptor.WithBase(base),
+		interceptor.WithDecisionHook(func(d interceptor.Decision) { decisions = append(decisions, d) }),
+	).Client()
+	request, _ = http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", strings.NewReader("payload"))
+	if response, err = client.Do(request); err != nil {
+		t.Fatalf("Expected the request to be answered, but got %v", err)
+	}
+	if response.StatusCode != http.StatusUnauthorized || len(decisions) != 1 {
+		t.Errorf("Expected the rejection to be returned, got %v", response.StatusCode)
+	}
+}
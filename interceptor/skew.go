@@ -0,0 +1,110 @@
+package interceptor
+
+import (
+	"github.com/mastercard/oauth1-signer-go"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	oauthProblemParam              = "oauth_problem"
+	oauthAcceptableTimestampsParam = "oauth_acceptable_timestamps"
+	timestampRefused               = "timestamp_refused"
+)
+
+// WithClockSkewCompensation corrects the oauth_timestamp of signed requests
+// by the offset between the local clock and the clock of the servers. The
+// offset is learned from the Date header of the responses to signed
+// requests, corrected by the Age header of the responses served from a
+// cache, and from the oauth_acceptable_timestamps of 401 responses
+// refusing the timestamp as per
+// https://wiki.oauth.net/w/page/12238543/ProblemReporting. It is rounded
+// to the second since the Date header has no finer resolution.
+func WithClockSkewCompensation() Option {
+	return func(t *Transport) {
+		t.skew = &clockSkew{}
+	}
+}
+
+// ClockSkew returns the offset measured by WithClockSkewCompensation, which
+// is added to the local clock. It is positive when the local clock is late.
+func (t *Transport) ClockSkew() time.Duration {
+	if t.skew == nil {
+		return 0
+	}
+	return time.Duration(t.skew.offset.Load())
+}
+
+// The clockSkew holds the offset to add to the local clock.
+type clockSkew struct {
+	offset atomic.Int64
+}
+
+// The clock returns the base clock shifted by the offset. The base clock
+// defaults to the system clock.
+func (s *clockSkew) clock(base oauth.Clock) oauth.Clock {
+	return oauth.ClockFunc(func() time.Time {
+		return now(base).Add(time.Duration(s.offset.Load()))
+	})
+}
+
+// The observe updates the offset from a response received for a request
+// sent at the given local time.
+func (s *clockSkew) observe(res *http.Response, sent, received time.Time) {
+	midpoint := sent.Add(received.Sub(sent) / 2)
+	if res.StatusCode == http.StatusUnauthorized {
+		if serverTime, ok := acceptableTimestamp(res.Header.Get("WWW-Authenticate")); ok {
+			s.offset.Store(int64(serverTime.Sub(midpoint).Round(time.Second)))
+			return
+		}
+	}
+	date, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	// a cached response was generated Age seconds ago, see
+	// https://www.rfc-editor.org/rfc/rfc9111#section-5.1
+	if age := res.Header.Get("Age"); age != "" {
+		seconds, err := strconv.ParseUint(age, 10, 32)
+		if err != nil {
+			return
+		}
+		date = date.Add(time.Duration(seconds) * time.Second)
+	}
+	// the server time lies within the second following the Date
+	serverTime := date.Add(500 * time.Millisecond)
+	s.offset.Store(int64(serverTime.Sub(midpoint).Round(time.Second)))
+}
+
+// The acceptableTimestamp returns the middle of the oauth_acceptable_timestamps
+// range of a WWW-Authenticate header refusing the timestamp.
+func acceptableTimestamp(authenticate string) (time.Time, bool) {
+	params, err := oauth.ParseAuthorizationHeader(authenticate)
+	if err != nil || params.Get(oauthProblemParam) != timestampRefused {
+		return time.Time{}, false
+	}
+	from, to, found := strings.Cut(params.Get(oauthAcceptableTimestampsParam), "-")
+	if !found {
+		return time.Time{}, false
+	}
+	start, err := strconv.ParseInt(from, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := strconv.ParseInt(to, 10, 64)
+	if err != nil || end < start {
+		return time.Time{}, false
+	}
+	return time.Unix(start, 0).Add(time.Duration(end-start) * time.Second / 2), true
+}
+
+// The now returns the time of the clock, or of the system clock.
+func now(clock oauth.Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return clock.Now()
+}
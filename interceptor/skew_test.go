@@ -0,0 +1,133 @@
+package interceptor_test
+
+import (
+	"fmt"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/interceptor"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// The skewedBase answers with the Date of a server whose clock is ahead of
+// the local clock by the given offset, and records the oauth_timestamp of
+// the requests.
+func skewedBase(offset time.Duration, status int, authenticate string, timestamps *[]int64) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if params, err := oauth.ParseAuthorizationHeader(req.Header.Get(oauth.AuthorizationHeaderName)); err == nil {
+			timestamp, _ := strconv.ParseInt(params.Timestamp(), 10, 64)
+			*timestamps = append(*timestamps, timestamp)
+		}
+		header := http.Header{"Date": {time.Now().Add(offset).UTC().Format(http.TimeFormat)}}
+		if authenticate != "" {
+			header.Set("WWW-Authenticate", authenticate)
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: http.NoBody, Request: req}, nil
+	})
+}
+
+func TestWithClockSkewCompensation_ShouldLearnFromDate(t *testing.T) {
+
+	// GIVEN
+	var timestamps []int64
+	transport := interceptor.NewTransport(newTestSigner(),
+		interceptor.WithBase(skewedBase(time.Hour, http.StatusOK, "", &timestamps)),
+		interceptor.WithClockSkewCompensation())
+	client := transport.Client()
+
+	// WHEN
+	_, err := client.Get("https://sandbox.api.mastercard.com/service")
+	_, err2 := client.Get("https://sandbox.api.mastercard.com/service")
+
+	// THEN
+	if err != nil || err2 != nil {
+		t.Fatalf("Expected the requests to succeed, but got %v, %v", err, err2)
+	}
+	if skew := transport.ClockSkew(); skew < time.Hour-time.Second || skew > time.Hour+time.Second {
+		t.Errorf("Expected a skew of one hour, got %v", skew)
+	}
+	if len(timestamps) != 2 || timestamps[1]-timestamps[0] < 3599 || timestamps[1]-timestamps[0] > 3601 {
+		t.Errorf("Expected the second timestamp to be compensated, got %v", timestamps)
+	}
+}
+
+func TestWithClockSkewCompensation_ShouldCorrectCachedResponses(t *testing.T) {
+
+	tests := []struct {
+		age      string
+		expected time.Duration
+	}{
+		{"3600", 0},
+		{"invalid", 0},
+		{"", -time.Hour},
+	}
+	for _, test := range tests {
+		// the response was cached an hour ago by a server without skew
+		base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			header := http.Header{"Date": {time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)}}
+			if test.age != "" {
+				header.Set("Age", test.age)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+		})
+		transport := interceptor.NewTransport(newTestSigner(), interceptor.WithBase(base), interceptor.WithClockSkewCompensation())
+
+		// WHEN
+		_, err := transport.Client().Get("https://sandbox.api.mastercard.com/service")
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected the request to succeed, but got %v", err)
+		}
+		if skew := transport.ClockSkew(); skew < test.expected-time.Second || skew > test.expected+time.Second {
+			t.Errorf("Expected a skew of %v with Age %q, got %v", test.expected, test.age, skew)
+		}
+	}
+}
+
+func TestWithClockSkewCompensation_ShouldLearnFromRefusedTimestamp(t *testing.T) {
+
+	// GIVEN
+	var timestamps []int64
+	serverTime := time.Now().Add(-10 * time.Minute).Unix()
+	authenticate := fmt.Sprintf("OAuth oauth_problem=\"timestamp_refused\", oauth_acceptable_timestamps=\"%v-%v\"", serverTime-300, serverTime+300)
+	transport := interceptor.NewTransport(newTestSigner(),
+		interceptor.WithBase(skewedBase(0, http.StatusUnauthorized, authenticate, &timestamps)),
+		interceptor.WithClockSkewCompensation())
+
+	// WHEN
+	_, err := transport.Client().Get("https://sandbox.api.mastercard.com/service")
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the request to succeed, but got %v", err)
+	}
+	if skew := transport.ClockSkew(); skew < -10*time.Minute-time.Second || skew > -10*time.Minute+time.Second {
+		t.Errorf("Expected a skew of minus ten minutes, got %v", skew)
+	}
+}
+
+func TestWithClockSkewCompensation_ShouldIgnoreUnsignedRequests(t *testing.T) {
+
+	// GIVEN
+	var timestamps []int64
+	transport := interceptor.NewTransport(newTestSigner(),
+		interceptor.WithBase(skewedBase(time.Hour, http.StatusOK, "", &timestamps)),
+		interceptor.WithAllowedHosts("api.mastercard.com"),
+		interceptor.WithClockSkewCompensation())
+
+	// WHEN
+	_, err := transport.Client().Get("https://third-party.org/service")
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the request to succeed, but got %v", err)
+	}
+	if skew := transport.ClockSkew(); skew != 0 {
+		t.Errorf("Expected the Date of an unsigned response to be ignored, got %v", skew)
+	}
+	if skew := interceptor.NewTransport(newTestSigner()).ClockSkew(); skew != 0 {
+		t.Errorf("Expected no skew without compensation, got %v", skew)
+	}
+}
@@ -0,0 +1,142 @@
+package interceptor
+
+import (
+	"errors"
+	"github.com/mastercard/oauth1-signer-go"
+	"net/http"
+	"time"
+)
+
+// Transport is an http.RoundTripper signing every request with an
+// oauth.Signer before handing it to the wrapped http.RoundTripper. It can
+// be composed with other http.RoundTripper middleware.
+type Transport struct {
+	signer  *oauth.Signer
+	base    http.RoundTripper
+	timeout time.Duration
+
+	allowedHosts   []hostMatcher
+	deniedHosts    []hostMatcher
+	allowedSchemes map[string]bool
+	decisionHook   func(Decision)
+
+	skew  *clockSkew
+	retry *RetryPolicy
+}
+
+// Option configures a Transport.
+type Option func(*Transport)
+
+// WithBase sets the http.RoundTripper the signed requests are handed to,
+// such as an http.Transport configured with a proxy or client certificates.
+// http.DefaultTransport is used by default.
+func WithBase(base http.RoundTripper) Option {
+	return func(t *Transport) {
+		t.base = base
+	}
+}
+
+// WithTimeout sets the timeout of the http.Client returned by Client.
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *Transport) {
+		t.timeout = timeout
+	}
+}
+
+// WithSigningEnabledFor restricts signing to requests made to the given
+// hosts. It is the same as WithAllowedHosts.
+func WithSigningEnabledFor(hosts ...string) Option {
+	return WithAllowedHosts(hosts...)
+}
+
+// WithSigningDisabledFor sends requests made to the given hosts without
+// signing them and with any Authorization header removed, as requests to
+// hosts that are not allowed. Hosts are matched as by WithAllowedHosts, and
+// take precedence over the allowed hosts.
+func WithSigningDisabledFor(hosts ...string) Option {
+	return func(t *Transport) {
+		for _, host := range hosts {
+			t.deniedHosts = append(t.deniedHosts, hostRule(host))
+		}
+	}
+}
+
+// NewTransport returns a Transport signing requests with the given signer.
+func NewTransport(signer *oauth.Signer, opts ...Option) *Transport {
+	t := &Transport{signer: signer, base: http.DefaultTransport}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Client returns an http.Client sending requests through the Transport.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t, Timeout: t.timeout}
+}
+
+// RoundTrip signs the request, unless its host is not allowed or signing is
+// disabled for it, and sends it through the wrapped http.RoundTripper.
+// Requests that are not signed are sent without Authorization header.
+// Requests following a redirect are signed again for allowed hosts, and
+// requests rejected because of their nonce or timestamp are signed again
+// and retried as per WithRetryPolicy. Requests whose signature is rejected
+// just after a key rotation are signed again with the previous key, see
+// utils.KeyProvider. The request given is not modified.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req == nil || req.URL == nil {
+		return nil, errors.New("interceptor: nil http.Request provided")
+	}
+	if allowed, reason := t.allows(req); !allowed {
+		stripped := req.Header.Get(oauth.AuthorizationHeaderName) != ""
+		if stripped {
+			req = req.Clone(req.Context())
+			req.Header.Del(oauth.AuthorizationHeaderName)
+		}
+		t.report(req, Decision{Stripped: stripped, Reason: reason})
+		return t.base.RoundTrip(req)
+	}
+	if t.signer == nil {
+		return nil, errors.New("interceptor: nil oauth.Signer provided")
+	}
+	res, err := t.signAndSend(t.signer, req.Clone(req.Context()), Decision{})
+	for retry := 1; err == nil && t.retry != nil && t.retry.retries(req, res, retry-1); retry++ {
+		discard(res)
+		if err = t.retry.wait(req.Context(), retry); err != nil {
+			return nil, err
+		}
+		var rewound *http.Request
+		if rewound, err = rewind(req); err != nil {
+			return nil, err
+		}
+		res, err = t.signAndSend(t.signer, rewound, Decision{Retry: retry})
+	}
+	if err == nil {
+		res, err = t.retryWithPreviousKey(req, res)
+	}
+	return res, err
+}
+
+// The signAndSend signs the given copy of a request with a new nonce and
+// timestamp, and sends it through the wrapped http.RoundTripper.
+func (t *Transport) signAndSend(signer *oauth.Signer, signed *http.Request, decision Decision) (*http.Response, error) {
+	if t.skew != nil {
+		skewed := *signer
+		skewed.Clock = t.skew.clock(signer.Clock)
+		signer = &skewed
+	}
+	if err := signer.Sign(signed); err != nil {
+		return nil, err
+	}
+	decision.Signed = true
+	t.report(signed, decision)
+	if t.skew == nil {
+		return t.base.RoundTrip(signed)
+	}
+	sent := now(t.signer.Clock)
+	res, err := t.base.RoundTrip(signed)
+	if err == nil {
+		t.skew.observe(res, sent, now(t.signer.Clock))
+	}
+	return res, err
+}
@@ -0,0 +1,101 @@
+package interceptor_test
+
+import (
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/interceptor"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTestSigner() *oauth.Signer {
+	signingKey, _ := utils.LoadSigningKey(path, password)
+	return &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}
+}
+
+func TestNewTransport_ShouldWrapBase(t *testing.T) {
+
+	// GIVEN
+	var sent *http.Request
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	transport := interceptor.NewTransport(newTestSigner(), interceptor.WithBase(base), interceptor.WithTimeout(5*time.Second))
+	request, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service", nil)
+
+	// WHEN
+	_, err := transport.RoundTrip(request)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the request to succeed, but got %v", err)
+	}
+	if sent == nil || sent.Header.Get(oauth.AuthorizationHeaderName) == "" {
+		t.Errorf("Expected the signed request to be handed to the base transport")
+	}
+	if request.Header.Get(oauth.AuthorizationHeaderName) != "" {
+		t.Errorf("Expected the original request not to be modified")
+	}
+	if client := transport.Client(); client.Timeout != 5*time.Second || client.Transport != transport {
+		t.Errorf("Expected a client with the configured timeout")
+	}
+}
+
+func TestNewTransport_ShouldApplyHostRules(t *testing.T) {
+
+	// GIVEN
+	var authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get(oauth.AuthorizationHeaderName)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	tests := []struct {
+		opts     []interceptor.Option
+		expected bool
+	}{
+		{nil, true},
+		{[]interceptor.Option{interceptor.WithSigningEnabledFor("api.mastercard.com")}, false},
+		{[]interceptor.Option{interceptor.WithSigningEnabledFor("127.0.0.1")}, true},
+		{[]interceptor.Option{interceptor.WithSigningEnabledFor(serverURL.Host)}, true},
+		{[]interceptor.Option{interceptor.WithSigningDisabledFor("127.0.0.1")}, false},
+		{[]interceptor.Option{interceptor.WithSigningEnabledFor("127.0.0.1"), interceptor.WithSigningDisabledFor(serverURL.Host)}, false},
+	}
+	for i, test := range tests {
+		authHeader = ""
+		client := interceptor.NewTransport(newTestSigner(), test.opts...).Client()
+
+		// WHEN
+		_, err := client.Get(server.URL + "/service")
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected the request to succeed, but got %v", err)
+		}
+		if signed := authHeader != ""; signed != test.expected {
+			t.Errorf("Test %d: expected signed=%v, got %v", i, test.expected, signed)
+		}
+	}
+}
+
+func TestTransportInvalidInput(t *testing.T) {
+
+	request, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service", nil)
+	if _, err := interceptor.NewTransport(nil).RoundTrip(request); err == nil {
+		t.Errorf("Expected an error to be thrown in case of nil signer")
+	}
+	if _, err := interceptor.NewTransport(newTestSigner()).RoundTrip(nil); err == nil {
+		t.Errorf("Expected an error to be thrown in case of nil request")
+	}
+}
@@ -0,0 +1,290 @@
+// Package middleware authenticates incoming http requests signed with
+// OAuth1.0a, such as the webhooks sent by Mastercard.
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mastercard/oauth1-signer-go"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Problems reported in the WWW-Authenticate header, as per
+// https://wiki.oauth.net/w/page/12238543/ProblemReporting
+const (
+	problemParameterAbsent         = "parameter_absent"
+	problemParameterRejected       = "parameter_rejected"
+	problemSignatureMethodRejected = "signature_method_rejected"
+	problemVersionRejected         = "version_rejected"
+	problemConsumerKeyUnknown      = "consumer_key_unknown"
+	problemConsumerKeyRejected     = "consumer_key_rejected"
+	problemBodyHashInvalid         = "body_hash_invalid"
+	problemSignatureInvalid        = "signature_invalid"
+	problemNonceUsed               = "nonce_used"
+	problemTimestampRefused        = "timestamp_refused"
+)
+
+// DefaultMaxBodyBytes is the size limit of the bodies read by the middleware
+// unless WithMaxBodyBytes is used.
+const DefaultMaxBodyBytes = 10 << 20
+
+var (
+	// ErrUnknownConsumerKey is reported when the KeyResolver has no key for
+	// the consumer key of the request.
+	ErrUnknownConsumerKey = errors.New("middleware: unknown consumer key")
+	// ErrInvalidOrigin is returned by New when the external origin is not
+	// an absolute http or https URL.
+	ErrInvalidOrigin = errors.New("middleware: invalid external origin")
+)
+
+// The keyRejecter is implemented by the errors of resolvers rejecting a
+// known consumer key, such as utils.ErrCertificateExpired.
+type keyRejecter interface {
+	KeyRejected() bool
+}
+
+// Consumer is the authenticated consumer of a request.
+type Consumer struct {
+	Key    string
+	Params *oauth.OAuthParams
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the consumer.
+func NewContext(ctx context.Context, consumer *Consumer) context.Context {
+	return context.WithValue(ctx, contextKey{}, consumer)
+}
+
+// FromContext returns the authenticated consumer of the request context.
+func FromContext(ctx context.Context) (*Consumer, bool) {
+	consumer, ok := ctx.Value(contextKey{}).(*Consumer)
+	return consumer, ok
+}
+
+// Option configures the middleware.
+type Option func(*Middleware)
+
+// WithRealm sets the realm of the WWW-Authenticate challenge.
+func WithRealm(realm string) Option {
+	return func(m *Middleware) {
+		m.realm = realm
+	}
+}
+
+// WithNonceStore rejects replayed requests, see oauth.Verifier.
+func WithNonceStore(store oauth.NonceStore) Option {
+	return func(m *Middleware) {
+		m.verifier.NonceStore = store
+	}
+}
+
+// WithTimestampWindow rejects requests whose oauth_timestamp is further
+// than the window from the time of the server, see oauth.Verifier.
+func WithTimestampWindow(window time.Duration) Option {
+	return func(m *Middleware) {
+		m.verifier.TimestampWindow = window
+	}
+}
+
+// WithClock replaces the system clock used to check oauth_timestamp and
+// to report the acceptable timestamps, see oauth.Verifier.
+func WithClock(clock oauth.Clock) Option {
+	return func(m *Middleware) {
+		m.verifier.Clock = clock
+	}
+}
+
+// WithExternalOrigin sets the scheme and host the requests are signed for,
+// such as "https://webhooks.example.com", when the server runs behind a
+// proxy or a load balancer terminating TLS. By default, the scheme and the
+// host are taken from the request received. New returns ErrInvalidOrigin
+// when the origin has no host or a scheme other than http or https.
+func WithExternalOrigin(origin string) Option {
+	return func(m *Middleware) {
+		m.origin, m.originErr = url.Parse(origin)
+	}
+}
+
+// WithMaxBodyBytes limits the size of the bodies read to verify their
+// oauth_body_hash or their form parameters. Requests with larger bodies are
+// answered with a 413 response. The limit defaults to DefaultMaxBodyBytes,
+// and a negative limit disables it.
+func WithMaxBodyBytes(n int64) Option {
+	return func(m *Middleware) {
+		m.maxBodyBytes = n
+	}
+}
+
+// WithErrorHook sets a function called with the error of every request
+// that fails authentication, for instance to log it.
+func WithErrorHook(hook func(*http.Request, error)) Option {
+	return func(m *Middleware) {
+		m.errorHook = hook
+	}
+}
+
+// Middleware authenticates the requests passed to the handlers it wraps.
+type Middleware struct {
+	resolver     oauth.KeyResolver
+	verifier     oauth.Verifier
+	realm        string
+	origin       *url.URL
+	originErr    error
+	maxBodyBytes int64
+	errorHook    func(*http.Request, error)
+}
+
+// New returns middleware verifying the Authorization header, the
+// oauth_body_hash and the signature of every request with the public key
+// resolved for its oauth_consumer_key. Authenticated requests are passed
+// to the next handler with the Consumer in their context. Other requests
+// are answered with a 400 or 401 response and a WWW-Authenticate: OAuth
+// challenge, as per https://tools.ietf.org/html/rfc5849#section-3.2, or
+// with a 413 response when their body is too large. The body is only read
+// once the Authorization header has been checked.
+func New(resolver oauth.KeyResolver, opts ...Option) (*Middleware, error) {
+	m := &Middleware{resolver: resolver, maxBodyBytes: DefaultMaxBodyBytes}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.originErr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOrigin, m.originErr)
+	}
+	if m.origin != nil && ((m.origin.Scheme != "http" && m.origin.Scheme != "https") || m.origin.Host == "") {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOrigin, m.origin)
+	}
+	return m, nil
+}
+
+// Handler returns a handler authenticating the requests passed to next.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consumer, body, err := m.authenticate(w, r)
+		if err != nil {
+			if m.errorHook != nil {
+				m.errorHook(r, err)
+			}
+			m.reject(w, err)
+			return
+		}
+		authenticated := r.WithContext(NewContext(r.Context(), consumer))
+		authenticated.Body = body
+		next.ServeHTTP(w, authenticated)
+	})
+}
+
+// The authenticate verifies the request and returns its consumer and its
+// body, to be read again by the next handler.
+func (m *Middleware) authenticate(w http.ResponseWriter, r *http.Request) (*Consumer, io.ReadCloser, error) {
+	authHeader := r.Header.Get(oauth.AuthorizationHeaderName)
+	if authHeader == "" {
+		return nil, nil, oauth.ErrMissingAuthorizationHeader
+	}
+	params, err := oauth.ParseAuthorizationHeader(authHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+	consumerKey := params.ConsumerKey()
+	if consumerKey == "" {
+		return nil, nil, fmt.Errorf("%w: oauth_consumer_key", oauth.ErrMissingParameter)
+	}
+	publicKey, err := m.resolver.ResolveKey(r.Context(), consumerKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if publicKey == nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrUnknownConsumerKey, consumerKey)
+	}
+	verifier := m.verifier
+	verifier.PublicKey = publicKey
+	verified := m.verifiedRequest(w, r)
+	if err = verifier.Verify(verified); err != nil {
+		return nil, nil, err
+	}
+	// the verifier buffers the body it reads
+	return &Consumer{Key: consumerKey, Params: params}, verified.Body, nil
+}
+
+// The verifiedRequest returns a shallow copy of the request with the
+// external origin, if any, and a body limited to the max body size.
+func (m *Middleware) verifiedRequest(w http.ResponseWriter, r *http.Request) *http.Request {
+	verified := *r
+	if m.origin != nil {
+		u := *r.URL
+		u.Scheme, u.Host = m.origin.Scheme, m.origin.Host
+		verified.URL = &u
+	}
+	if m.maxBodyBytes >= 0 && r.Body != nil && r.Body != http.NoBody {
+		verified.Body = http.MaxBytesReader(w, r.Body, m.maxBodyBytes)
+	}
+	return &verified
+}
+
+// The reject answers a request that failed authentication.
+func (m *Middleware) reject(w http.ResponseWriter, err error) {
+	status, problem := http.StatusUnauthorized, ""
+	var rejecter keyRejecter
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, oauth.ErrMissingAuthorizationHeader):
+	case errors.Is(err, oauth.ErrMissingParameter):
+		status, problem = http.StatusBadRequest, problemParameterAbsent
+	case errors.Is(err, oauth.ErrMalformedAuthorizationHeader), errors.Is(err, oauth.ErrDuplicateParameter):
+		status, problem = http.StatusBadRequest, problemParameterRejected
+	case errors.Is(err, oauth.ErrUnsupportedSignatureMethod):
+		status, problem = http.StatusBadRequest, problemSignatureMethodRejected
+	case errors.Is(err, oauth.ErrUnsupportedVersion):
+		status, problem = http.StatusBadRequest, problemVersionRejected
+	case errors.Is(err, ErrUnknownConsumerKey):
+		problem = problemConsumerKeyUnknown
+	case errors.As(err, &rejecter) && rejecter.KeyRejected():
+		problem = problemConsumerKeyRejected
+	case errors.Is(err, oauth.ErrBodyHashMismatch):
+		problem = problemBodyHashInvalid
+	case errors.Is(err, oauth.ErrInvalidSignature):
+		problem = problemSignatureInvalid
+	case errors.Is(err, oauth.ErrNonceReused):
+		problem = problemNonceUsed
+	case errors.Is(err, oauth.ErrTimestampOutOfWindow):
+		problem = problemTimestampRefused
+	case errors.As(err, &tooLarge):
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", m.challenge(problem))
+	http.Error(w, http.StatusText(status), status)
+}
+
+// The challenge returns the WWW-Authenticate header reporting the problem,
+// if any. Refused timestamps are reported with the acceptable range.
+func (m *Middleware) challenge(problem string) string {
+	var buf bytes.Buffer
+	buf.WriteString("OAuth realm=\"")
+	buf.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(m.realm))
+	buf.WriteString("\"")
+	if problem != "" {
+		fmt.Fprintf(&buf, ", oauth_problem=\"%v\"", problem)
+	}
+	if problem == problemTimestampRefused {
+		window := m.verifier.TimestampWindow
+		if window == 0 {
+			window = oauth.DefaultTimestampWindow
+		}
+		now := time.Now()
+		if m.verifier.Clock != nil {
+			now = m.verifier.Clock.Now()
+		}
+		fmt.Fprintf(&buf, ", oauth_acceptable_timestamps=\"%v-%v\"", now.Add(-window).Unix(), now.Add(window).Unix())
+	}
+	return buf.String()
+}
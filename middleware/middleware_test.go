@@ -0,0 +1,348 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/middleware"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	consumerKey = "WFQHgP6gI01ZxbpqUzdWQ_GpDVrym3dhY6Q9X3PZe4ba3850!3b9f3d6593d04a0cbefadaf8bb3975fb0000000000000000"
+)
+
+var (
+	signingKey, _ = utils.LoadSigningKey("../testdata/test_key_container.p12", "Password1")
+	resolver      = oauth.KeyResolverFunc(func(_ context.Context, key string) (*rsa.PublicKey, error) {
+		if key == consumerKey {
+			return &signingKey.PublicKey, nil
+		}
+		return nil, nil
+	})
+)
+
+// The newServer returns a server authenticating requests and echoing the
+// consumer key and the body of authenticated requests.
+func newServer(opts ...middleware.Option) *httptest.Server {
+	authenticate, err := middleware.New(resolver, opts...)
+	if err != nil {
+		panic(err)
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consumer, ok := middleware.FromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, consumer.Key+" "+string(body))
+	})
+	return httptest.NewServer(authenticate.Handler(handler))
+}
+
+func newSignedRequest(url, payload string) *http.Request {
+	req, _ := http.NewRequest("POST", url, strings.NewReader(payload))
+	_ = (&oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}).Sign(req)
+	return req
+}
+
+func TestNew_ShouldAuthenticateSignedRequests(t *testing.T) {
+
+	// GIVEN
+	server := newServer()
+	defer server.Close()
+
+	// WHEN
+	res, err := http.DefaultClient.Do(newSignedRequest(server.URL+"/webhook?event=push", "payload"))
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the request to succeed, got %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || string(body) != consumerKey+" payload" {
+		t.Errorf("Expected the consumer and the body to reach the handler, got %v %v", res.StatusCode, string(body))
+	}
+}
+
+func TestNew_ShouldVerifyExternalOrigin(t *testing.T) {
+
+	// GIVEN
+	server := newServer(middleware.WithExternalOrigin("https://webhooks.example.com"))
+	defer server.Close()
+	req := newSignedRequest("https://webhooks.example.com/webhook", "payload")
+	req.URL, _ = url.Parse(server.URL + "/webhook")
+	req.Host = ""
+
+	// WHEN
+	res, err := http.DefaultClient.Do(req)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the request to succeed, got %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || string(body) != consumerKey+" payload" {
+		t.Errorf("Expected the request signed for the external origin to be authenticated, got %v %v", res.StatusCode, string(body))
+	}
+}
+
+func TestNew_ShouldRejectUnauthenticatedRequests(t *testing.T) {
+
+	server := newServer(middleware.WithRealm("Webhooks"))
+	defer server.Close()
+
+	unsigned, _ := http.NewRequest("POST", server.URL, strings.NewReader("payload"))
+	tampered := newSignedRequest(server.URL, "payload")
+	tampered.Body = io.NopCloser(strings.NewReader("tampered"))
+	tampered.ContentLength = int64(len("tampered"))
+	unknown, _ := http.NewRequest("POST", server.URL, nil)
+	_ = (&oauth.Signer{ConsumerKey: "unknown", SigningKey: signingKey}).Sign(unknown)
+	malformed, _ := http.NewRequest("POST", server.URL, nil)
+	malformed.Header.Set(oauth.AuthorizationHeaderName, "OAuth oauth_consumer_key")
+
+	tests := []struct {
+		name      string
+		req       *http.Request
+		status    int
+		challenge string
+	}{
+		{"unsigned", unsigned, http.StatusUnauthorized, `OAuth realm="Webhooks"`},
+		{"tampered", tampered, http.StatusUnauthorized, `OAuth realm="Webhooks", oauth_problem="body_hash_invalid"`},
+		{"unknown", unknown, http.StatusUnauthorized, `OAuth realm="Webhooks", oauth_problem="consumer_key_unknown"`},
+		{"malformed", malformed, http.StatusBadRequest, `OAuth realm="Webhooks", oauth_problem="parameter_rejected"`},
+	}
+	for _, test := range tests {
+
+		// WHEN
+		res, err := http.DefaultClient.Do(test.req)
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected the %v request to be answered, got %v", test.name, err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != test.status || res.Header.Get("WWW-Authenticate") != test.challenge {
+			t.Errorf("Expected %v %v for the %v request, got %v %v", test.status, test.challenge, test.name, res.StatusCode, res.Header.Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestNew_ShouldRejectReplaysAndStaleTimestamps(t *testing.T) {
+
+	// GIVEN
+	var errs []error
+	now := time.Unix(1700000000, 0)
+	server := newServer(middleware.WithNonceStore(oauth.NewMemoryNonceStore(0, 0)), middleware.WithTimestampWindow(time.Minute),
+		middleware.WithClock(oauth.ClockFunc(func() time.Time { return now })),
+		middleware.WithErrorHook(func(_ *http.Request, err error) { errs = append(errs, err) }))
+	defer server.Close()
+	req, _ := http.NewRequest("POST", server.URL, nil)
+	_ = (&oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Clock: oauth.ClockFunc(func() time.Time { return now })}).Sign(req)
+	stale, _ := http.NewRequest("POST", server.URL, nil)
+	_ = (&oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}).Sign(stale)
+
+	// WHEN
+	first, _ := http.DefaultClient.Do(req)
+	replay, _ := http.DefaultClient.Do(req)
+	refused, _ := http.DefaultClient.Do(stale)
+
+	// THEN
+	if first.StatusCode != http.StatusOK {
+		t.Errorf("Expected the first request to be authenticated, got %v", first.StatusCode)
+	}
+	if replay.StatusCode != http.StatusUnauthorized || !strings.Contains(replay.Header.Get("WWW-Authenticate"), `oauth_problem="nonce_used"`) {
+		t.Errorf("Expected the replay to be rejected, got %v %v", replay.StatusCode, replay.Header.Get("WWW-Authenticate"))
+	}
+	if challenge := refused.Header.Get("WWW-Authenticate"); !strings.Contains(challenge, `oauth_problem="timestamp_refused", oauth_acceptable_timestamps="1699999940-1700000060"`) {
+		t.Errorf("Expected the stale request to be rejected with the acceptable timestamps, got %v", challenge)
+	}
+	if len(errs) != 2 || !errors.Is(errs[0], oauth.ErrNonceReused) || !errors.Is(errs[1], oauth.ErrTimestampOutOfWindow) {
+		t.Errorf("Expected the errors to be reported, got %v", errs)
+	}
+}
+
+// The readCounter counts the bytes read from a body.
+type readCounter struct {
+	io.Reader
+	read int
+}
+
+func (r *readCounter) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.read += n
+	return n, err
+}
+
+func (r *readCounter) Close() error {
+	return nil
+}
+
+func TestNew_ShouldCheckHeaderBeforeReadingBody(t *testing.T) {
+
+	// GIVEN
+	now := time.Unix(1700000000, 0)
+	clock := oauth.ClockFunc(func() time.Time { return now })
+	authenticate, _ := middleware.New(resolver, middleware.WithNonceStore(oauth.NewMemoryNonceStore(0, 0)), middleware.WithClock(clock))
+	var received string
+	handler := authenticate.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	}))
+	signed := newSignedRequest("https://example.com/webhook", "payload")
+	_ = (&oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Clock: clock}).Sign(signed)
+	stale := newSignedRequest("https://example.com/webhook", "payload")
+
+	tests := []struct {
+		name    string
+		req     *http.Request
+		problem string
+	}{
+		{"first", signed, ""},
+		{"replayed", signed, `oauth_problem="nonce_used"`},
+		{"stale", stale, `oauth_problem="timestamp_refused"`},
+	}
+	for _, test := range tests {
+		body := &readCounter{Reader: strings.NewReader("payload")}
+		req := httptest.NewRequest("POST", "https://example.com/webhook", body)
+		req.Header = test.req.Header
+		recorder := httptest.NewRecorder()
+
+		// WHEN
+		handler.ServeHTTP(recorder, req)
+
+		// THEN
+		challenge := recorder.Header().Get("WWW-Authenticate")
+		if test.problem == "" {
+			if recorder.Code != http.StatusOK || received != "payload" {
+				t.Errorf("Expected the %v request to reach the handler with its body, got %v %q", test.name, recorder.Code, received)
+			}
+			if req.Body != body {
+				t.Errorf("Expected the body of the %v request not to be replaced", test.name)
+			}
+			continue
+		}
+		if !strings.Contains(challenge, test.problem) || body.read != 0 {
+			t.Errorf("Expected the %v request to be rejected before reading %v bytes, got %v", test.name, body.read, challenge)
+		}
+	}
+}
+
+func TestNew_ShouldLimitBodySize(t *testing.T) {
+
+	tests := []struct {
+		opts   []middleware.Option
+		status int
+	}{
+		{nil, http.StatusOK},
+		{[]middleware.Option{middleware.WithMaxBodyBytes(4)}, http.StatusRequestEntityTooLarge},
+		{[]middleware.Option{middleware.WithMaxBodyBytes(7)}, http.StatusOK},
+		{[]middleware.Option{middleware.WithMaxBodyBytes(-1)}, http.StatusOK},
+	}
+	for _, test := range tests {
+		server := newServer(test.opts...)
+
+		// WHEN
+		res, err := http.DefaultClient.Do(newSignedRequest(server.URL+"/webhook", "payload"))
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected the request to be answered, got %v", err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != test.status {
+			t.Errorf("Expected %v, got %v", test.status, res.StatusCode)
+		}
+		server.Close()
+	}
+}
+
+func TestNew_ShouldRejectExpiredCertificates(t *testing.T) {
+
+	// GIVEN
+	expired := oauth.KeyResolverFunc(func(_ context.Context, key string) (*rsa.PublicKey, error) {
+		return nil, fmt.Errorf("%w: %v", utils.ErrCertificateExpired, key)
+	})
+	authenticate, _ := middleware.New(expired)
+	handler := authenticate.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Errorf("Expected the handler not to be called")
+	}))
+	recorder := httptest.NewRecorder()
+
+	// WHEN
+	handler.ServeHTTP(recorder, newSignedRequest("https://example.com/webhook", "payload"))
+
+	// THEN
+	if challenge := recorder.Header().Get("WWW-Authenticate"); recorder.Code != http.StatusUnauthorized || challenge != `OAuth realm="", oauth_problem="consumer_key_rejected"` {
+		t.Errorf("Expected the consumer key to be rejected, got %v %v", recorder.Code, challenge)
+	}
+}
+
+func TestNew_ShouldFailOnResolverError(t *testing.T) {
+
+	// GIVEN
+	failing := oauth.KeyResolverFunc(func(context.Context, string) (*rsa.PublicKey, error) {
+		return nil, errors.New("key store unavailable")
+	})
+	authenticate, _ := middleware.New(failing)
+	handler := authenticate.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Errorf("Expected the handler not to be called")
+	}))
+	recorder := httptest.NewRecorder()
+
+	// WHEN
+	handler.ServeHTTP(recorder, newSignedRequest("https://example.com/webhook", "payload"))
+
+	// THEN
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected an internal server error, got %v", recorder.Code)
+	}
+}
+
+func TestNew_ShouldRejectInvalidOrigins(t *testing.T) {
+
+	for _, origin := range []string{"webhooks.example.com", "ftp://webhooks.example.com", "https://", "https://webhooks.example.com:port"} {
+
+		// WHEN
+		_, err := middleware.New(resolver, middleware.WithExternalOrigin(origin))
+
+		// THEN
+		if !errors.Is(err, middleware.ErrInvalidOrigin) {
+			t.Errorf("Expected ErrInvalidOrigin for %v, got %v", origin, err)
+		}
+	}
+}
+
+func TestNew_ShouldEscapeRealm(t *testing.T) {
+
+	// GIVEN
+	server := newServer(middleware.WithRealm(`Web"hooks\`))
+	defer server.Close()
+
+	// WHEN
+	res, err := http.Post(server.URL, "text/plain", nil)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the request to be answered, got %v", err)
+	}
+	_ = res.Body.Close()
+	if challenge := res.Header.Get("WWW-Authenticate"); challenge != `OAuth realm="Web\"hooks\\"` {
+		t.Errorf("Expected the realm to be escaped, got %v", challenge)
+	}
+	if params, err := oauth.ParseAuthorizationHeader(res.Header.Get("WWW-Authenticate")); err != nil || params.Realm() != `Web"hooks\` {
+		t.Errorf("Expected the challenge to be parsed back, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Clock supplies the time of oauth_timestamp. Implementations other than
+// the system clock allow reproducible headers, for instance in golden tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// NonceGenerator supplies the oauth_nonce of every request, as per
+// https://tools.ietf.org/html/rfc5849#section-3.3
+type NonceGenerator interface {
+	Nonce() (string, error)
+}
+
+// NonceFunc adapts a function to the NonceGenerator interface.
+type NonceFunc func() (string, error)
+
+// Nonce returns f().
+func (f NonceFunc) Nonce() (string, error) {
+	return f()
+}
+
+// RandomNonceGenerator generates alphanumeric nonces of 16 characters from
+// Rand, which defaults to crypto/rand.Reader.
+type RandomNonceGenerator struct {
+	Rand io.Reader
+}
+
+// Nonce returns a new random nonce.
+func (g RandomNonceGenerator) Nonce() (string, error) {
+	r := g.Rand
+	if r == nil {
+		r = rand.Reader
+	}
+	randomVal := make([]byte, nonceLength)
+	if _, err := io.ReadFull(r, randomVal); err != nil {
+		return "", err
+	}
+	nonce := make([]byte, nonceLength)
+	for i, v := range randomVal {
+		nonce[i] = alphaNumericChars[int(v)%len(alphaNumericChars)]
+	}
+	return string(nonce), nil
+}
+
+// HeaderOption configures the generation of an Authorization header.
+type HeaderOption func(*headerOptions)
+
+// WithClock sets the Clock of oauth_timestamp, the system clock by default.
+func WithClock(clock Clock) HeaderOption {
+	return func(o *headerOptions) {
+		o.clock = clock
+	}
+}
+
+// WithNonceGenerator sets the NonceGenerator of oauth_nonce.
+func WithNonceGenerator(nonceGenerator NonceGenerator) HeaderOption {
+	return func(o *headerOptions) {
+		o.nonceGenerator = nonceGenerator
+	}
+}
+
+// WithRandom sets the random source of the default NonceGenerator.
+func WithRandom(r io.Reader) HeaderOption {
+	return func(o *headerOptions) {
+		o.nonceGenerator = RandomNonceGenerator{Rand: r}
+	}
+}
+
+// The headerOptions holds the sources of the generated oauth parameters.
+// Nil sources fall back to the system clock and crypto/rand.
+type headerOptions struct {
+	clock          Clock
+	nonceGenerator NonceGenerator
+}
+
+// The newHeaderOptions applies the given options.
+func newHeaderOptions(opts []HeaderOption) *headerOptions {
+	o := &headerOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// The timestamp returns the UNIX timestamp of the clock.
+func (o *headerOptions) timestamp() string {
+	if o == nil || o.clock == nil {
+		return getTimestamp()
+	}
+	return strconv.FormatInt(o.clock.Now().Unix(), 10)
+}
+
+// The nonce returns a nonce of the nonce generator.
+func (o *headerOptions) nonce() (string, error) {
+	if o == nil || o.nonceGenerator == nil {
+		return getNonce(), nil
+	}
+	return o.nonceGenerator.Nonce()
+}
@@ -0,0 +1,350 @@
+package oauth
+
+import (
+	"bufio"
+	"container/list"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimestampWindow is the timestamp window of a Verifier with a
+	// NonceStore and no TimestampWindow.
+	DefaultTimestampWindow = 5 * time.Minute
+	minCompactedLines      = 1024
+)
+
+var (
+	// ErrNonceReused is returned when the nonce of a request has already
+	// been used with the same consumer key and timestamp.
+	ErrNonceReused = errors.New("verifier: nonce already used")
+	// ErrTimestampOutOfWindow is returned when oauth_timestamp is too far
+	// from the time of the verifier.
+	ErrTimestampOutOfWindow = errors.New("verifier: timestamp out of window")
+)
+
+// NonceStore records the nonces of verified requests so that replays can be
+// rejected, as per https://tools.ietf.org/html/rfc5849#section-3.3. Nonces
+// are keyed by consumer key, nonce and timestamp. Implementations must be
+// safe for concurrent use.
+type NonceStore interface {
+	// CheckAndStore records the nonce, or returns ErrNonceReused when it
+	// has already been recorded.
+	CheckAndStore(consumerKey, nonce string, timestamp time.Time) error
+}
+
+// The nonceChecker is implemented by the stores of this package, whose
+// nonces can be looked up without being recorded, so that replays are
+// rejected before the body of the request is read.
+type nonceChecker interface {
+	seen(consumerKey, nonce string, timestamp time.Time) bool
+}
+
+// The nonceKey returns the key of a nonce. The consumer key and the nonce
+// are prefixed with their length, as decoded header values may contain any
+// separator.
+func nonceKey(consumerKey, nonce string, timestamp time.Time) string {
+	return strconv.Itoa(len(consumerKey)) + ":" + consumerKey +
+		strconv.Itoa(len(nonce)) + ":" + nonce +
+		strconv.FormatInt(timestamp.Unix(), 10)
+}
+
+// The nonceTTL returns the given TTL or its default.
+func nonceTTL(ttl time.Duration) time.Duration {
+	if ttl == 0 {
+		return 2 * DefaultTimestampWindow
+	}
+	return ttl
+}
+
+// The nonceEntry is a recorded nonce and the time it can be forgotten.
+type nonceEntry struct {
+	key    string
+	expiry time.Time
+}
+
+// The nonceCache holds nonces in the order they were recorded, which is
+// also the order they expire in.
+type nonceCache struct {
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// The expire forgets the nonces expired at the given time.
+func (c *nonceCache) expire(now time.Time) {
+	for e := c.order.Front(); e != nil && !e.Value.(*nonceEntry).expiry.After(now); e = c.order.Front() {
+		c.removeOldest()
+	}
+}
+
+// The removeOldest forgets the least recently recorded nonce.
+func (c *nonceCache) removeOldest() {
+	if e := c.order.Front(); e != nil {
+		c.order.Remove(e)
+		delete(c.entries, e.Value.(*nonceEntry).key)
+	}
+}
+
+// The remove forgets the nonce, if recorded.
+func (c *nonceCache) remove(key string) {
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+func (c *nonceCache) contains(key string) bool {
+	_, ok := c.entries[key]
+	return ok
+}
+
+func (c *nonceCache) add(key string, expiry time.Time) {
+	c.entries[key] = c.order.PushBack(&nonceEntry{key: key, expiry: expiry})
+}
+
+// MemoryNonceStore is an in-memory NonceStore forgetting nonces after a
+// TTL, which should exceed twice the timestamp window of the Verifier and
+// defaults to twice DefaultTimestampWindow.
+//
+// When MaxEntries nonces are recorded and none has expired, the oldest
+// nonce is forgotten to record a new one, so that a flood of requests
+// cannot fail the others. The request of a nonce forgotten before it
+// expires can be replayed, so MaxEntries should exceed the number of
+// requests expected within the TTL.
+type MemoryNonceStore struct {
+	TTL time.Duration
+	// MaxEntries bounds the memory used, zero meaning no limit.
+	MaxEntries int
+	// Clock, when set, replaces the system clock.
+	Clock Clock
+
+	mu    sync.Mutex
+	cache *nonceCache
+}
+
+// NewMemoryNonceStore returns a MemoryNonceStore.
+func NewMemoryNonceStore(ttl time.Duration, maxEntries int) *MemoryNonceStore {
+	return &MemoryNonceStore{TTL: ttl, MaxEntries: maxEntries}
+}
+
+// CheckAndStore records the nonce, or returns ErrNonceReused.
+func (s *MemoryNonceStore) CheckAndStore(consumerKey, nonce string, timestamp time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache == nil {
+		s.cache = newNonceCache()
+	}
+	now := clockNow(s.Clock)
+	s.cache.expire(now)
+	key := nonceKey(consumerKey, nonce, timestamp)
+	if s.cache.contains(key) {
+		return ErrNonceReused
+	}
+	for s.MaxEntries > 0 && len(s.cache.entries) >= s.MaxEntries {
+		s.cache.removeOldest()
+	}
+	s.cache.add(key, now.Add(nonceTTL(s.TTL)))
+	return nil
+}
+
+func (s *MemoryNonceStore) seen(consumerKey, nonce string, timestamp time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache == nil {
+		return false
+	}
+	s.cache.expire(clockNow(s.Clock))
+	return s.cache.contains(nonceKey(consumerKey, nonce, timestamp))
+}
+
+// Len returns the number of nonces recorded.
+func (s *MemoryNonceStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache == nil {
+		return 0
+	}
+	return len(s.cache.entries)
+}
+
+// FileNonceStore is a NonceStore persisting nonces to a file, so that
+// replays are rejected across restarts. Nonces are appended to the file,
+// which is compacted once expired nonces make up most of it. The file must
+// not be shared between processes.
+type FileNonceStore struct {
+	// Clock, when set, replaces the system clock.
+	Clock Clock
+	// OnCompactionError, when set, is called with the errors of the
+	// compaction of the file. Such errors do not fail CheckAndStore, as the
+	// nonce is recorded, and the compaction is retried once the file has
+	// grown again.
+	OnCompactionError func(error)
+
+	path string
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	file  *os.File
+	lines int
+	cache *nonceCache
+}
+
+// NewFileNonceStore opens or creates the file at path and loads its nonces,
+// the expired ones being forgotten on the first call to CheckAndStore.
+// Nonces are forgotten after ttl, see MemoryNonceStore.
+func NewFileNonceStore(path string, ttl time.Duration) (*FileNonceStore, error) {
+	s := &FileNonceStore{path: path, ttl: nonceTTL(ttl), cache: newNonceCache()}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	return s, nil
+}
+
+// CheckAndStore records the nonce, or returns ErrNonceReused. The nonce is
+// written to the file before CheckAndStore returns.
+func (s *FileNonceStore) CheckAndStore(consumerKey, nonce string, timestamp time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	now := clockNow(s.Clock)
+	s.cache.expire(now)
+	key := nonceKey(consumerKey, nonce, timestamp)
+	if s.cache.contains(key) {
+		return ErrNonceReused
+	}
+	expiry := now.Add(s.ttl)
+	if _, err := s.file.WriteString(formatNonceLine(key, expiry)); err != nil {
+		return err
+	}
+	s.cache.add(key, expiry)
+	s.lines++
+	if s.lines > minCompactedLines && s.lines > 2*len(s.cache.entries) {
+		if err := s.compact(); err != nil {
+			// retry once the file has grown again
+			s.lines = len(s.cache.entries)
+			if s.OnCompactionError != nil {
+				s.OnCompactionError(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *FileNonceStore) seen(consumerKey, nonce string, timestamp time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache.expire(clockNow(s.Clock))
+	return s.cache.contains(nonceKey(consumerKey, nonce, timestamp))
+}
+
+// Close closes the file.
+func (s *FileNonceStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// The load reads the nonces of the file, if any. The clock may not be set
+// yet, so expired nonces are only forgotten by CheckAndStore.
+func (s *FileNonceStore) load() error {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		s.lines++
+		key, expiry, err := parseNonceLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("oauth: invalid nonce file %v: %w", s.path, err)
+		}
+		// a nonce recorded again after expiring is kept with its last expiry
+		s.cache.remove(key)
+		s.cache.add(key, expiry)
+	}
+	return scanner.Err()
+}
+
+// The compact rewrites the file with the nonces that have not expired.
+// The new file replaces the file, and its handle the current handle, only
+// once it is written, so that nonces keep being appended on failure.
+func (s *FileNonceStore) compact() error {
+	tmp := s.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for e := s.cache.order.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*nonceEntry)
+		_, _ = w.WriteString(formatNonceLine(entry.key, entry.expiry))
+	}
+	if err = w.Flush(); err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = s.file.Close()
+	s.file = file
+	s.lines = len(s.cache.entries)
+	return nil
+}
+
+// The formatNonceLine returns the line of a nonce in the file: the UNIX
+// expiry and the escaped key.
+func formatNonceLine(key string, expiry time.Time) string {
+	return strconv.FormatInt(expiry.Unix(), 10) + " " + url.QueryEscape(key) + "\n"
+}
+
+func parseNonceLine(line string) (string, time.Time, error) {
+	expiry, escaped, found := strings.Cut(line, " ")
+	if !found {
+		return "", time.Time{}, errors.New("missing separator")
+	}
+	seconds, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	key, err := url.QueryUnescape(escaped)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return key, time.Unix(seconds, 0), nil
+}
+
+// The clockNow returns the time of the clock, or of the system clock.
+func clockNow(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return clock.Now()
+}
@@ -0,0 +1,234 @@
+package oauth_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryNonceStore_ShouldRejectReusedNonces(t *testing.T) {
+
+	// GIVEN
+	now := time.Unix(1111111111, 0)
+	store := oauth.NewMemoryNonceStore(time.Minute, 2)
+	store.Clock = oauth.ClockFunc(func() time.Time { return now })
+	timestamp := time.Unix(1111111111, 0)
+
+	// WHEN
+	err := store.CheckAndStore(consumerKey, "nonce", timestamp)
+	reused := store.CheckAndStore(consumerKey, "nonce", timestamp)
+
+	// THEN
+	if err != nil || !errors.Is(reused, oauth.ErrNonceReused) {
+		t.Errorf("Expected the nonce to be rejected the second time, got %v, %v", err, reused)
+	}
+	if err = store.CheckAndStore("another key", "nonce", timestamp); err != nil {
+		t.Errorf("Expected nonces to be keyed by consumer key, got %v", err)
+	}
+	if err = store.CheckAndStore(consumerKey, "nonce", timestamp); !errors.Is(err, oauth.ErrNonceReused) {
+		t.Errorf("Expected ErrNonceReused, got %v", err)
+	}
+
+	// the oldest nonce is evicted when the store is full
+	if err = store.CheckAndStore(consumerKey, "other nonce", timestamp); err != nil || store.Len() != 2 {
+		t.Errorf("Expected the nonce to be recorded, got %v with %v nonces", err, store.Len())
+	}
+	if err = store.CheckAndStore("another key", "nonce", timestamp); !errors.Is(err, oauth.ErrNonceReused) {
+		t.Errorf("Expected the newest nonces to be kept, got %v", err)
+	}
+	if err = store.CheckAndStore(consumerKey, "nonce", timestamp); err != nil {
+		t.Errorf("Expected the oldest nonce to be evicted, got %v", err)
+	}
+
+	// the parts of the key cannot be confused
+	if err = store.CheckAndStore("a\x00b", "c", timestamp); err != nil {
+		t.Errorf("Expected the nonce to be recorded, got %v", err)
+	}
+	if err = store.CheckAndStore("a", "b\x00c", timestamp); err != nil {
+		t.Errorf("Expected nonces of different consumer keys not to collide, got %v", err)
+	}
+
+	// nonces expire after the TTL
+	now = now.Add(time.Minute)
+	if err = store.CheckAndStore(consumerKey, "other nonce", timestamp); err != nil || store.Len() != 1 {
+		t.Errorf("Expected the nonces to expire, got %v with %v nonces", err, store.Len())
+	}
+}
+
+func TestFileNonceStore_ShouldPersistNonces(t *testing.T) {
+
+	// GIVEN
+	path := filepath.Join(t.TempDir(), "nonces")
+	expired := fmt.Sprintf("%v %v\n", time.Now().Add(-time.Minute).Unix(), "expired")
+	_ = os.WriteFile(path, []byte(expired), 0600)
+	store, err := oauth.NewFileNonceStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Expected the store to be opened, got %v", err)
+	}
+	timestamp := time.Now()
+
+	// WHEN
+	err = store.CheckAndStore(consumerKey, "nonce", timestamp)
+	_ = store.Close()
+	reopened, _ := oauth.NewFileNonceStore(path, time.Hour)
+	defer reopened.Close()
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the nonce to be recorded, got %v", err)
+	}
+	if err = reopened.CheckAndStore(consumerKey, "nonce", timestamp); !errors.Is(err, oauth.ErrNonceReused) {
+		t.Errorf("Expected the nonce to be rejected after reopening, got %v", err)
+	}
+	if err = store.CheckAndStore(consumerKey, "other nonce", timestamp); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected os.ErrClosed, got %v", err)
+	}
+
+	_ = os.WriteFile(path, []byte("invalid"), 0600)
+	if _, err = oauth.NewFileNonceStore(path, time.Hour); err == nil {
+		t.Errorf("Expected an error in case of invalid file")
+	}
+}
+
+func TestFileNonceStore_ShouldCompactExpiredNonces(t *testing.T) {
+
+	// GIVEN
+	path := filepath.Join(t.TempDir(), "nonces")
+	store, _ := oauth.NewFileNonceStore(path, time.Nanosecond)
+	defer store.Close()
+
+	// WHEN
+	for i := 0; i < 2000; i++ {
+		if err := store.CheckAndStore(consumerKey, fmt.Sprint(i), time.Now()); err != nil {
+			t.Fatalf("Expected the nonce to be recorded, got %v", err)
+		}
+	}
+
+	// THEN
+	file, _ := os.Open(path)
+	defer file.Close()
+	lines := 0
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		lines++
+	}
+	if lines > 1024 {
+		t.Errorf("Expected the file to be compacted, got %v lines", lines)
+	}
+}
+
+func TestHttpRequestVerification_ShouldRejectReplays(t *testing.T) {
+
+	// GIVEN
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}
+	verifier := &oauth.Verifier{PublicKey: &signingKey.PublicKey, NonceStore: oauth.NewMemoryNonceStore(0, 0)}
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", strings.NewReader("payload"))
+	_ = signer.Sign(req)
+
+	// WHEN
+	err := verifier.Verify(req)
+	replayed := verifier.Verify(req)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the request to be verified, got %v", err)
+	}
+	if !errors.Is(replayed, oauth.ErrNonceReused) {
+		t.Errorf("Expected ErrNonceReused, got %v", replayed)
+	}
+}
+
+func TestHttpRequestVerification_ShouldApplyTimestampWindow(t *testing.T) {
+
+	// GIVEN
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Clock: oauth.ClockFunc(func() time.Time { return time.Now().Add(-10 * time.Minute) })}
+	req, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service", nil)
+	_ = signer.Sign(req)
+
+	tests := []struct {
+		verifier *oauth.Verifier
+		expected error
+	}{
+		{&oauth.Verifier{PublicKey: &signingKey.PublicKey}, nil},
+		{&oauth.Verifier{PublicKey: &signingKey.PublicKey, TimestampWindow: time.Minute}, oauth.ErrTimestampOutOfWindow},
+		{&oauth.Verifier{PublicKey: &signingKey.PublicKey, TimestampWindow: time.Hour}, nil},
+		{&oauth.Verifier{PublicKey: &signingKey.PublicKey, NonceStore: oauth.NewMemoryNonceStore(0, 0)}, oauth.ErrTimestampOutOfWindow},
+		{&oauth.Verifier{PublicKey: &signingKey.PublicKey, TimestampWindow: time.Minute, Clock: signer.Clock}, nil},
+	}
+	for i, test := range tests {
+
+		// WHEN
+		err := test.verifier.Verify(req)
+
+		// THEN
+		if !errors.Is(err, test.expected) {
+			t.Errorf("Expected %v for verifier %v, got %v", test.expected, i, err)
+		}
+	}
+}
+
+func TestFileNonceStore_ShouldUseClock(t *testing.T) {
+
+	// GIVEN
+	now := time.Unix(1111111111, 0)
+	path := filepath.Join(t.TempDir(), "nonces")
+	store, _ := oauth.NewFileNonceStore(path, time.Minute)
+	store.Clock = oauth.ClockFunc(func() time.Time { return now })
+	timestamp := time.Unix(1111111111, 0)
+
+	// WHEN
+	err := store.CheckAndStore(consumerKey, "nonce", timestamp)
+	reused := store.CheckAndStore(consumerKey, "nonce", timestamp)
+	now = now.Add(time.Minute)
+	expired := store.CheckAndStore(consumerKey, "nonce", timestamp)
+	_ = store.Close()
+	data, _ := os.ReadFile(path)
+
+	// THEN
+	if err != nil || !errors.Is(reused, oauth.ErrNonceReused) || expired != nil {
+		t.Errorf("Expected the nonce to expire after the TTL of the clock, got %v, %v, %v", err, reused, expired)
+	}
+	if !strings.HasPrefix(string(data), "1111111171 ") {
+		t.Errorf("Expected the expiry to be computed with the clock, got %v", string(data))
+	}
+}
+
+func TestFileNonceStore_ShouldReportCompactionErrors(t *testing.T) {
+
+	// GIVEN
+	path := filepath.Join(t.TempDir(), "nonces")
+	store, _ := oauth.NewFileNonceStore(path, time.Nanosecond)
+	defer store.Close()
+	var errs []error
+	store.OnCompactionError = func(err error) { errs = append(errs, err) }
+	// the temporary file of the compaction cannot be created
+	_ = os.Mkdir(path+".tmp", 0700)
+
+	// WHEN
+	for i := 0; i < 2000; i++ {
+		if err := store.CheckAndStore(consumerKey, fmt.Sprint(i), time.Now()); err != nil {
+			t.Fatalf("Expected the nonce to be recorded despite the compaction error, got %v", err)
+		}
+	}
+
+	// THEN
+	if len(errs) == 0 {
+		t.Errorf("Expected the compaction error to be reported")
+	}
+	_ = os.Remove(path + ".tmp")
+	for i := 2000; i < 4000; i++ {
+		if err := store.CheckAndStore(consumerKey, fmt.Sprint(i), time.Now()); err != nil {
+			t.Fatalf("Expected the store to keep working after the compaction error, got %v", err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines > 1024 {
+		t.Errorf("Expected the file to be compacted once possible, got %v lines", lines)
+	}
+}
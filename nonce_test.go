@@ -0,0 +1,82 @@
+package oauth_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	fixedClock = oauth.ClockFunc(func() time.Time { return time.Unix(1111111111, 0) })
+	fixedNonce = oauth.NonceFunc(func() (string, error) { return "1111111111111111", nil })
+)
+
+func TestGetAuthorizationHeader_ShouldBeReproducible(t *testing.T) {
+
+	// GIVEN
+	u, _ := url.Parse("https://sandbox.api.mastercard.com/service?a=1")
+
+	// WHEN
+	first, err := oauth.GetAuthorizationHeader(u, "POST", []byte("payload"), consumerKey, signingKey, oauth.WithClock(fixedClock), oauth.WithNonceGenerator(fixedNonce))
+	second, _ := oauth.GetAuthorizationHeader(u, "POST", []byte("payload"), consumerKey, signingKey, oauth.WithClock(fixedClock), oauth.WithNonceGenerator(fixedNonce))
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the authorization header, got %v", err)
+	}
+	if first != second {
+		t.Errorf("Expected identical headers, got %v and %v", first, second)
+	}
+	header, _ := oauth.ParseAuthorizationHeader(first)
+	if header.Nonce() != "1111111111111111" || header.Timestamp() != "1111111111" {
+		t.Errorf("Expected the fixed nonce and timestamp, got %v", first)
+	}
+	if err = oauth.VerifyAuthorizationHeader(first, u, "POST", []byte("payload"), &signingKey.PublicKey); err != nil {
+		t.Errorf("Expected the header to be verified, got %v", err)
+	}
+}
+
+func TestHttpRequestSigning_ShouldUseClockAndNonceGenerator(t *testing.T) {
+
+	// GIVEN
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Clock: fixedClock, Rand: bytes.NewReader(make([]byte, 32))}
+	first, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service", nil)
+	second, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service", nil)
+
+	// WHEN
+	err := signer.Sign(first)
+	_ = (&oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Clock: fixedClock, NonceGenerator: oauth.RandomNonceGenerator{Rand: bytes.NewReader(make([]byte, 16))}}).Sign(second)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to sign the http request, got %v", err)
+	}
+	authHeader := first.Header.Get(oauth.AuthorizationHeaderName)
+	if authHeader != second.Header.Get(oauth.AuthorizationHeaderName) {
+		t.Errorf("Expected identical headers, got %v and %v", authHeader, second.Header.Get(oauth.AuthorizationHeaderName))
+	}
+	if header, _ := oauth.ParseAuthorizationHeader(authHeader); header.Nonce() != "0000000000000000" {
+		t.Errorf("Expected the nonce of the random reader, got %v", header.Nonce())
+	}
+}
+
+func TestNonceGenerator_ShouldReturnErrors(t *testing.T) {
+
+	u, _ := url.Parse("https://sandbox.api.mastercard.com/service")
+	failing := oauth.NonceFunc(func() (string, error) { return "", errors.New("no entropy") })
+	if _, err := oauth.GetAuthorizationHeaderWithSigner(context.Background(), u, "GET", nil, consumerKey, signingKey, oauth.WithNonceGenerator(failing)); err == nil || err.Error() != "no entropy" {
+		t.Errorf("Expected the nonce generator error, got %v", err)
+	}
+	if _, err := oauth.GetAuthorizationHeader(u, "GET", nil, consumerKey, signingKey, oauth.WithRandom(strings.NewReader("short"))); err == nil {
+		t.Errorf("Expected an error in case of exhausted random reader")
+	}
+	if nonce, _ := (oauth.RandomNonceGenerator{}).Nonce(); len(nonce) != 16 {
+		t.Errorf("Expected a nonce of 16 characters, got %v", nonce)
+	}
+}
@@ -1,14 +1,17 @@
 // Package oauth performs OAuth1.0a compliant signing with
-// body hash support for non-urlencoded content types.
+// body hash support for non-urlencoded content types. The parameters of
+// application/x-www-form-urlencoded bodies are signed instead.
 package oauth
 
 import (
 	"bytes"
-	"crypto/rand"
+	"context"
+	gocrypto "crypto"
 	"crypto/rsa"
 	"encoding/base64"
 	"fmt"
 	"github.com/mastercard/oauth1-signer-go/crypto"
+	"io"
 	"net/url"
 	"sort"
 	"strconv"
@@ -30,34 +33,125 @@ const (
 	oauthTimestampParam       = "oauth_timestamp"
 	oauthVersionParam         = "oauth_version"
 	oauthBodyHashParam        = "oauth_body_hash"
+	oauthTokenParam           = "oauth_token"
+	oauthCallbackParam        = "oauth_callback"
+	oauthVerifierParam        = "oauth_verifier"
+	oauthParamPrefix          = "oauth_"
+	formContentType           = "application/x-www-form-urlencoded"
 	defaultOauthVersion       = "1.0"
+	sha1HashingAlgorithm      = "SHA1"
 	sha256HashingAlgorithm    = "SHA256"
+	sha512HashingAlgorithm    = "SHA512"
 )
 
+// The generatedOAuthParams lists the parameters produced by getOAuthParams,
+// which cannot be set through the additional parameters.
+var generatedOAuthParams = map[string]bool{
+	oauthConsumerKeyParam:     true,
+	oauthNonceParam:           true,
+	oauthSignatureMethodParam: true,
+	oauthTimestampParam:       true,
+	oauthVersionParam:         true,
+	oauthBodyHashParam:        true,
+}
+
 // GetAuthorizationHeader creates a Mastercard API compliant OAuth Authorization header.
-func GetAuthorizationHeader(u *url.URL, method string, payload []byte, consumerKey string, signingKey *rsa.PrivateKey) (string, error) {
-	queryParams := extractQueryParams(u)
+// Options such as WithClock and WithNonceGenerator make the header reproducible.
+func GetAuthorizationHeader(u *url.URL, method string, payload []byte, consumerKey string, signingKey *rsa.PrivateKey, opts ...HeaderOption) (string, error) {
+	return GetAuthorizationHeaderWithSigner(context.Background(), u, method, payload, consumerKey, signingKey, opts...)
+}
 
-	// get all required oauth params
-	oauthParams := getOAuthParams(consumerKey, payload)
+// GetAuthorizationHeaderWithSigner creates a Mastercard API compliant OAuth Authorization
+// header using a crypto.Signer backed by an RSA key, such as a key held in a HSM or
+// a cloud KMS. The context is passed to signers implementing crypto.ContextSigner.
+func GetAuthorizationHeaderWithSigner(ctx context.Context, u *url.URL, method string, payload []byte, consumerKey string, signer gocrypto.Signer, opts ...HeaderOption) (string, error) {
+	return GetAuthorizationHeaderWithMethod(ctx, u, method, payload, consumerKey, RSASHA256(signer, nil), opts...)
+}
 
-	// combine query and oauth parameters into lexicographically sorted string
-	paramString := toOauthParamString(queryParams, oauthParams)
+// GetAuthorizationHeaderWithMethod creates an OAuth Authorization header signed
+// with the given SignatureMethod, such as HMACSHA1 for providers using consumer
+// and token secrets.
+func GetAuthorizationHeaderWithMethod(ctx context.Context, u *url.URL, method string, payload []byte, consumerKey string, signatureMethod SignatureMethod, opts ...HeaderOption) (string, error) {
+	return GetAuthorizationHeaderWithParams(ctx, u, method, payload, consumerKey, signatureMethod, nil, opts...)
+}
 
-	// normalized URL without query params and fragment
-	baseUrl := getBaseUrlString(u)
+// GetAuthorizationHeaderWithParams creates an OAuth Authorization header carrying
+// additional protocol parameters, such as oauth_token, oauth_callback or
+// oauth_verifier in the three-legged flow of https://tools.ietf.org/html/rfc5849#section-2.
+// The parameter names must start with "oauth_" and empty values are omitted.
+func GetAuthorizationHeaderWithParams(ctx context.Context, u *url.URL, method string, payload []byte, consumerKey string, signatureMethod SignatureMethod, params map[string]string, opts ...HeaderOption) (string, error) {
+	bodyHash, err := getBodyHash(payload, signatureMethod.HashAlgorithm())
+	if err != nil {
+		return "", err
+	}
+	return getAuthorizationHeader(ctx, u, method, bodyHash, nil, consumerKey, signatureMethod, params, newHeaderOptions(opts))
+}
+
+// The getAuthorizationHeader creates the Authorization header for a payload
+// whose body hash has already been computed. Form-encoded payloads have no
+// body hash and their encoded parameters are given in formParams instead.
+func getAuthorizationHeader(ctx context.Context, u *url.URL, method, bodyHash string, formParams map[string][]string, consumerKey string, signatureMethod SignatureMethod, params map[string]string, opts *headerOptions) (string, error) {
+	oauthParams, _, err := getSignedOAuthParams(ctx, u, method, bodyHash, formParams, consumerKey, signatureMethod, params, opts)
+	if err != nil {
+		return "", err
+	}
+	return getAuthorizationString(oauthParams), nil
+}
+
+// The getSignedOAuthParams returns the oauth parameters including
+// oauth_signature, with their values encoded as in the Authorization header,
+// and the details of the signature. The nonce and the timestamp come from opts.
+func getSignedOAuthParams(ctx context.Context, u *url.URL, method, bodyHash string, formParams map[string][]string, consumerKey string, signatureMethod SignatureMethod, params map[string]string, opts *headerOptions) (map[string]string, *SignatureDetails, error) {
+	if err := checkSignatureMethod(u, signatureMethod); err != nil {
+		return nil, nil, err
+	}
+
+	// get all required oauth params
+	nonce, err := opts.nonce()
+	if err != nil {
+		return nil, nil, err
+	}
+	oauthParams := getOAuthParams(consumerKey, signatureMethod.Name(), bodyHash, nonce, opts.timestamp())
+	for k, v := range params {
+		if !strings.HasPrefix(k, oauthParamPrefix) {
+			return nil, nil, fmt.Errorf("oauth: %v is not an oauth protocol parameter", k)
+		}
+		if v != "" && !generatedOAuthParams[k] {
+			oauthParams[k] = v
+		}
+	}
+	// values are encoded as per https://tools.ietf.org/html/rfc5849#section-3.6,
+	// the consumer key and the body hash may contain '+', '/' or '='
+	for k, v := range oauthParams {
+		oauthParams[k] = percentEncode(v)
+	}
 
 	// signature base string
-	sbs := getSignatureBaseString(method, baseUrl, paramString)
+	details := getSignatureDetails(u, method, bodyHash, formParams, oauthParams)
 
 	// signature
-	signature, err := signSignatureBaseString(sbs, signingKey)
+	details.Signature, err = signatureMethod.Sign(ctx, details.SignatureBaseString)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-	oauthParams[oauthSignatureParam] = percentEncode(signature)
+	oauthParams[oauthSignatureParam] = percentEncode(details.Signature)
 
-	return getAuthorizationString(oauthParams), nil
+	return oauthParams, details, nil
+}
+
+// The getSignatureDetails normalizes the request into the signature base
+// string, as per https://tools.ietf.org/html/rfc5849#section-3.4.1
+func getSignatureDetails(u *url.URL, method, bodyHash string, formParams map[string][]string, oauthParams map[string]string) *SignatureDetails {
+	details := &SignatureDetails{BodyHash: bodyHash}
+
+	// combine query, form and oauth parameters into lexicographically sorted string
+	details.ParameterString = toOauthParamString(extractRequestParams(u, formParams), oauthParams)
+
+	// normalized URL without query params and fragment
+	details.BaseURL = getBaseUrlString(u)
+
+	details.SignatureBaseString = getSignatureBaseString(method, details.BaseURL, details.ParameterString)
+	return details
 }
 
 // The extractQueryParams parses query parameters out of the URL.
@@ -94,15 +188,47 @@ func extractQueryParams(u *url.URL) map[string][]string {
 	return queryParams
 }
 
-// The getOAuthParams returns map of oauth parameters.
-func getOAuthParams(consumerKey string, payload []byte) map[string]string {
+// The extractRequestParams combines the query parameters of the URL with
+// the parameters of a form-encoded body as per https://tools.ietf.org/html/rfc5849#section-3.4.1.3.1
+func extractRequestParams(u *url.URL, formParams map[string][]string) map[string][]string {
+	requestParams := extractQueryParams(u)
+	for k, v := range formParams {
+		requestParams[k] = append(requestParams[k], v...)
+	}
+	return requestParams
+}
+
+// The extractFormParams parses the parameters of a form-encoded body and
+// percent encodes their names and values as per https://tools.ietf.org/html/rfc5849#section-3.4.1.3.2
+func extractFormParams(body []byte) (map[string][]string, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	formParams := make(map[string][]string, len(values))
+	for k, v := range values {
+		encoded := make([]string, len(v))
+		for i, each := range v {
+			encoded[i] = percentEncode(each)
+		}
+		formParams[percentEncode(k)] = encoded
+	}
+	return formParams, nil
+}
+
+// The getOAuthParams returns map of oauth parameters. The oauth_body_hash
+// is omitted when empty, as required for form-encoded bodies by
+// https://tools.ietf.org/id/draft-eaton-oauth-bodyhash-00.html
+func getOAuthParams(consumerKey, signatureMethod, bodyHash, nonce, timestamp string) map[string]string {
 	params := map[string]string{
 		oauthConsumerKeyParam:     consumerKey,
-		oauthNonceParam:           getNonce(),
-		oauthSignatureMethodParam: "RSA-" + sha256HashingAlgorithm,
-		oauthTimestampParam:       getTimestamp(),
+		oauthNonceParam:           nonce,
+		oauthSignatureMethodParam: signatureMethod,
+		oauthTimestampParam:       timestamp,
 		oauthVersionParam:         defaultOauthVersion,
-		oauthBodyHashParam:        getBodyHash(payload),
+	}
+	if bodyHash != "" {
+		params[oauthBodyHashParam] = bodyHash
 	}
 	return params
 }
@@ -116,23 +242,31 @@ func epoch() int64 {
 	return time.Now().Unix()
 }
 
-// The getBodyHash generates the hash of request payload
-func getBodyHash(payload []byte) string {
-	hash := crypto.Sha256(payload)
-	return base64.StdEncoding.EncodeToString(hash)
+// The getBodyHash generates the hash of request payload with the hash
+// algorithm of the signature method
+func getBodyHash(payload []byte, algorithm string) (string, error) {
+	hash, err := crypto.Hash(algorithm, payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(hash), nil
+}
+
+// The getBodyHashFromReader generates the hash of a request payload read
+// from r, without buffering it.
+func getBodyHashFromReader(r io.Reader, algorithm string) (string, error) {
+	hash, err := crypto.HashReader(algorithm, r)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(hash), nil
 }
 
 // The getNonce generates a random string for replay protection as per
 // https://tools.ietf.org/html/rfc5849#section-3.3
-func getNonce() (nonce string) {
-	randomVal := make([]byte, nonceLength)
-	_, _ = rand.Read(randomVal)
-
-	var length = len(alphaNumericChars)
-	for _, v := range randomVal {
-		nonce += string(alphaNumericChars[int(v)%length])
-	}
-	return
+func getNonce() string {
+	nonce, _ := RandomNonceGenerator{}.Nonce()
+	return nonce
 }
 
 // The toOauthParamString sorts lexicographically all parameters and
@@ -210,9 +344,9 @@ func getSignatureBaseString(method, baseUrl, paramString string) string {
 }
 
 // The signSignatureBaseString performs the RSA signing on the given
-// input string.
-func signSignatureBaseString(sbs string, signingKey *rsa.PrivateKey) (string, error) {
-	signature, err := crypto.Sign([]byte(sbs), signingKey)
+// input string with the given hash algorithm.
+func signSignatureBaseString(ctx context.Context, sbs string, signer gocrypto.Signer, algorithm string) (string, error) {
+	signature, err := crypto.SignWithAlgorithm(ctx, []byte(sbs), signer, algorithm)
 	if err != nil {
 		return "", err
 	}
@@ -222,12 +356,25 @@ func signSignatureBaseString(sbs string, signingKey *rsa.PrivateKey) (string, er
 // The getAuthorizationString constructs a valid Authorization header as per
 // https://tools.ietf.org/html/rfc5849#section-3.5.1
 func getAuthorizationString(oauthParams map[string]string) string {
+	// the realm, if any, comes first and the other parameters are sorted
+	// so that the header is stable
+	keys := make([]string, 0, len(oauthParams))
+	for k := range oauthParams {
+		if k != realmParam {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := oauthParams[realmParam]; ok {
+		keys = append([]string{realmParam}, keys...)
+	}
+
 	var headerBuf bytes.Buffer
 	headerBuf.WriteString(authorizationPrefix)
-	for k, v := range oauthParams {
+	for _, k := range keys {
 		headerBuf.WriteString(k)
 		headerBuf.WriteString("=\"")
-		headerBuf.WriteString(v)
+		headerBuf.WriteString(oauthParams[k])
 		headerBuf.WriteString("\",")
 	}
 	header := headerBuf.String()
@@ -1,6 +1,7 @@
 package oauth
 
 import (
+	"context"
 	"crypto/rsa"
 	"github.com/mastercard/oauth1-signer-go/utils"
 	"net/url"
@@ -151,20 +152,31 @@ func TestParameterEncoding_ShouldCreateExpectedSignatureBaseString_WhenQueryPara
 
 func TestGetBodyHash(t *testing.T) {
 
-	bodyHash := getBodyHash([]byte{})
+	bodyHash, _ := getBodyHash([]byte{}, sha256HashingAlgorithm)
 	if "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=" != bodyHash {
 		t.Errorf("Something went wrong got, %v", bodyHash)
 	}
-	bodyHash = getBodyHash([]byte{})
+	bodyHash, _ = getBodyHash([]byte{}, sha256HashingAlgorithm)
 	if "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=" != bodyHash {
 		t.Errorf("Something went wrong got, %v", bodyHash)
 	}
-	bodyHash = getBodyHash([]byte("{\"foõ\":\"bar\"}"))
+	bodyHash, _ = getBodyHash([]byte("{\"foõ\":\"bar\"}"), sha256HashingAlgorithm)
 	if "+Z+PWW2TJDnPvRcTgol+nKO3LT7xm8smnsg+//XMIyI=" != bodyHash {
 		t.Errorf("Something went wrong got, %v", bodyHash)
 	}
 }
 
+func TestGetBodyHash_ShouldFollowHashAlgorithm(t *testing.T) {
+
+	bodyHash, _ := getBodyHash([]byte{}, sha1HashingAlgorithm)
+	if "2jmj7l5rSw0yVb/vlWAYkK/YBwk=" != bodyHash {
+		t.Errorf("Something went wrong got, %v", bodyHash)
+	}
+	if _, err := getBodyHash([]byte{}, "MD4"); err == nil {
+		t.Errorf("Expected an error in case of unsupported hash algorithm")
+	}
+}
+
 func TestGetOAuthParamString_ShouldSupportRfcExample(t *testing.T) {
 	params := make(map[string][]string)
 	params["b5"] = []string{"%3D%253D"}
@@ -188,6 +200,25 @@ func TestGetOAuthParamString_ShouldSupportRfcExample(t *testing.T) {
 	}
 }
 
+func TestGetOAuthParamString_ShouldSupportRfcExampleWithFormBody(t *testing.T) {
+	u, _ := url.Parse("http://example.com/request?b5=%3D%253D&a3=a&c%40=&a2=r%20b")
+	formParams, err := extractFormParams([]byte("c2&a3=2+q"))
+	if err != nil {
+		t.Fatalf("Expected the form parameters, got %v", err)
+	}
+
+	oauthParams := getOAuthParams("9djdj82h48djs9d2", "HMAC-SHA1", "", "7d8f3e4a", "137131201")
+	oauthParams["oauth_token"] = "kkk9d7dh3k39sjv7"
+	delete(oauthParams, "oauth_version")
+
+	paramString := toOauthParamString(extractRequestParams(u, formParams), oauthParams)
+	expectedParams := "a2=r%20b&a3=2%20q&a3=a&b5=%3D%253D&c%40=&c2=&oauth_consumer_key=9djdj82h48djs9d2&oauth_nonce=7d8f3e4a&oauth_signature_method=HMAC-SHA1&oauth_timestamp=137131201&oauth_token=kkk9d7dh3k39sjv7"
+
+	if expectedParams != paramString {
+		t.Errorf("Something went wrong got, %v", paramString)
+	}
+}
+
 func TestGetOAuthParamString_ShouldUseAscendingByteValueOrdering(t *testing.T) {
 	params := make(map[string][]string)
 	params["b"] = []string{"b"}
@@ -291,12 +322,31 @@ func TestGetSignatureBaseString_Nominal(t *testing.T) {
 
 func TestSignSignatureBaseString(t *testing.T) {
 	expectedSignatureString := "IJeNKYGfUhFtj5OAPRI92uwfjJJLCej3RCMLbp7R6OIYJhtwxnTkloHQ2bgV7fks4GT/A7rkqrgUGk0ewbwIC6nS3piJHyKVc7rvQXZuCQeeeQpFzLRiH3rsb+ZS+AULK+jzDje4Fb+BQR6XmxuuJmY6YrAKkj13Ln4K6bZJlSxOizbNvt+Htnx+hNd4VgaVBeJKcLhHfZbWQxK76nMnjY7nDcM/2R6LUIR2oLG1L9m55WP3bakAvmOr392ulv1+mWCwDAZZzQ4lakDD2BTu0ZaVsvBW+mcKFxYeTq7SyTQMM4lEwFPJ6RLc8jJJ+veJXHekLVzWg4qHRtzNBLz1mA=="
-	s, _ := signSignatureBaseString("baseString", getTestSigningKey())
+	s, _ := signSignatureBaseString(context.Background(), "baseString", getTestSigningKey(), sha256HashingAlgorithm)
 	if expectedSignatureString != s {
 		t.Errorf("Something went wrong got, %v", s)
 	}
 }
 
+func TestSignSignatureBaseString_ShouldFollowHashAlgorithm(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		expected  string
+	}{
+		{sha1HashingAlgorithm, "UlHD5WW8Wo5i/jRheQX1Cot3IAh5P6En6pGt6uwR3Z7g966md5ewXHv7p2CcjLj5pgRGx2nJYkWDrTHl7+bk+lyNMgr9dxM5mzx4T4+Wh3l5MIWnsylNrwNwV+rhcqXtoR/WOIPGnUy/LmjmYgdRYZgU0z1WK+oxipnS/rnp6dmz65Pnl432w8TLokE0xyHM8Oi5KH3NbdAEWJUshRfvNRNfk4A+MQgOqXq8neVAt3iHRVUOuQTpvt/lqPXqydFQWtVI0wTRsaox+i8ewt3NZ/BN8tcHJfq88DK3Eok3gOdPhIBMPxI2L/5yU96LNzd6AG0K+1tCXfFt5HN5YPjOhA=="},
+		{sha512HashingAlgorithm, "ViJ5HOVBsaCUg8uUHwAUbxz8A3675XiouubKOAs8lMB4pp/PUcSwZ5Yz7EmsuNNNBsli2MOo9FZuhG7tlHI9HbgtcPYj64I02aZoZ9eNuy6IBiou8xSRXpz1uX41FQGfcUta3jIGD69qujhfQz9tsShtqDzybd087hpdbLnPFpq9een1zxnLIwzISeJtRUc7saqgfP7kZrqqL1V9ppqjR1X3HnSAsiadS8Zg1gB5RzRfp/xH4uKhjfAXvHwBdCPxGzGH04TjqRaN84LSZk1XwmMcI0A1+fLG96aKCi9Fur7gGKzxmrvidTJYu4ltEXPBy8iWkYsq8qSRNCaewHoaww=="},
+	}
+	for _, test := range tests {
+		s, err := signSignatureBaseString(context.Background(), "baseString", getTestSigningKey(), test.algorithm)
+		if err != nil || test.expected != s {
+			t.Errorf("Something went wrong with %v, got %v", test.algorithm, s)
+		}
+	}
+	if _, err := signSignatureBaseString(context.Background(), "baseString", getTestSigningKey(), "MD4"); err == nil {
+		t.Errorf("Expected an error in case of unsupported hash algorithm")
+	}
+}
+
 func getTestSigningKey() *rsa.PrivateKey {
 	signingKey, _ := utils.LoadSigningKey("testdata/test_key_container.p12", "Password1")
 	return signingKey
@@ -308,7 +358,7 @@ func TestSignSignatureBaseString_ShouldThrowIllegalStateException_WhenInvalidKey
 			t.Errorf("It should panic in case of a nil private key")
 		}
 	}()
-	_, _ = signSignatureBaseString("some string", nil)
+	_, _ = signSignatureBaseString(context.Background(), "some string", nil, sha256HashingAlgorithm)
 	t.Errorf("It should panic in case of a nil private key")
 }
 
@@ -322,7 +372,7 @@ func TestGetSignatureBaseString_Integrated(t *testing.T) {
 	oauthParams["oauth_signature_method"] = "RSA-SHA256"
 	oauthParams["oauth_timestamp"] = "1111111111"
 	oauthParams["oauth_version"] = "1.0"
-	oauthParams["oauth_body_hash"] = getBodyHash([]byte(body))
+	oauthParams["oauth_body_hash"], _ = getBodyHash([]byte(body), sha256HashingAlgorithm)
 
 	queryParams := extractQueryParams(urlParse)
 	paramString := toOauthParamString(queryParams, oauthParams)
@@ -355,3 +405,11 @@ func TestGetNonce_ShouldBeUniqueAndHaveLengthOf16(t *testing.T) {
 		t.Errorf("Something went wrong got, %v", len(nonce))
 	}
 }
+
+func TestGetAuthorizationString_ShouldSortParamsWithRealmFirst(t *testing.T) {
+	oauthParams := map[string]string{"oauth_nonce": "nonce", "realm": "Example", "oauth_body_hash": "hash"}
+	header := getAuthorizationString(oauthParams)
+	if "OAuth realm=\"Example\",oauth_body_hash=\"hash\",oauth_nonce=\"nonce\"" != header {
+		t.Errorf("Something went wrong got, %v", header)
+	}
+}
@@ -0,0 +1,320 @@
+package oauth
+
+import (
+	"context"
+	gocrypto "crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/mastercard/oauth1-signer-go/crypto"
+	"net/url"
+	"strings"
+)
+
+const (
+	// HMACSHA1MethodName is the oauth_signature_method of HMACSHA1.
+	HMACSHA1MethodName = "HMAC-" + sha1HashingAlgorithm
+	// HMACSHA256MethodName is the oauth_signature_method of HMACSHA256.
+	HMACSHA256MethodName = "HMAC-" + sha256HashingAlgorithm
+	// RSASHA1MethodName is the oauth_signature_method of RSASHA1.
+	RSASHA1MethodName = "RSA-" + sha1HashingAlgorithm
+	// RSASHA256MethodName is the oauth_signature_method of RSASHA256.
+	RSASHA256MethodName = "RSA-" + sha256HashingAlgorithm
+	// RSASHA512MethodName is the oauth_signature_method of RSASHA512.
+	RSASHA512MethodName = "RSA-" + sha512HashingAlgorithm
+	// RSAPSSSHA256MethodName is the oauth_signature_method of RSAPSSSHA256.
+	RSAPSSSHA256MethodName = "RSA-PSS-" + sha256HashingAlgorithm
+	// PlaintextMethodName is the oauth_signature_method of Plaintext.
+	PlaintextMethodName = "PLAINTEXT"
+)
+
+// ErrPlaintextRequiresHTTPS is returned when the PLAINTEXT signature method
+// is used over a non-HTTPS URL, which would disclose the secrets.
+var ErrPlaintextRequiresHTTPS = errors.New("oauth: PLAINTEXT signature method requires HTTPS")
+
+// SignatureMethod produces and verifies the oauth_signature of a signature
+// base string, as per https://tools.ietf.org/html/rfc5849#section-3.4
+type SignatureMethod interface {
+	// Name returns the oauth_signature_method parameter value.
+	Name() string
+	// HashAlgorithm returns the name of the hash algorithm, as registered
+	// with crypto.RegisterHash, the oauth_body_hash is computed with.
+	HashAlgorithm() string
+	// Sign returns the oauth_signature of the signature base string,
+	// before percent encoding.
+	Sign(ctx context.Context, sbs string) (string, error)
+	// Verify returns ErrInvalidSignature when the signature does not
+	// match the signature base string.
+	Verify(sbs, signature string) error
+}
+
+// The keyedSignatureMethod is implemented by the RSA signature methods, whose
+// keys default to the keys of the Signer or the Verifier using them.
+type keyedSignatureMethod interface {
+	withKeys(key gocrypto.Signer, publicKey *rsa.PublicKey) SignatureMethod
+}
+
+// The secretSignatureMethod is implemented by the HMAC and PLAINTEXT signature
+// methods, whose token secret defaults to the one of the Signer using them.
+type secretSignatureMethod interface {
+	withTokenSecret(tokenSecret string) SignatureMethod
+}
+
+// The withTokenSecret sets the token secret of the signature method, unless
+// it already has one.
+func withTokenSecret(signatureMethod SignatureMethod, tokenSecret string) SignatureMethod {
+	if secret, ok := signatureMethod.(secretSignatureMethod); ok && tokenSecret != "" {
+		return secret.withTokenSecret(tokenSecret)
+	}
+	return signatureMethod
+}
+
+// RSA is the family of RSA signature methods, named after the hash
+// algorithm the signature base string is hashed with, such as RSA-SHA256,
+// the one used by Mastercard APIs. Hash is the name of the algorithm as
+// registered with crypto.RegisterHash, SHA256 when empty. PKCS#1 v1.5
+// signatures are produced, or RSASSA-PSS ones when PSS is set. Key is
+// required to sign and PublicKey, or the public key of Key, is used to
+// verify.
+type RSA struct {
+	Hash string
+	// PSS produces RSASSA-PSS signatures, the method being named
+	// RSA-PSS-<Hash>. SaltLength is the length of the salt in bytes, zero
+	// meaning a salt as long as the hash. The receiving side must use the
+	// same salt length.
+	PSS        bool
+	SaltLength int
+	Key        gocrypto.Signer
+	PublicKey  *rsa.PublicKey
+}
+
+// RSASHA1 returns the RSA-SHA1 signature method of legacy OAuth 1.0
+// providers, as per https://tools.ietf.org/html/rfc5849#section-3.4.3
+func RSASHA1(key gocrypto.Signer, publicKey *rsa.PublicKey) RSA {
+	return RSA{Hash: sha1HashingAlgorithm, Key: key, PublicKey: publicKey}
+}
+
+// RSASHA256 returns the RSA-SHA256 signature method.
+func RSASHA256(key gocrypto.Signer, publicKey *rsa.PublicKey) RSA {
+	return RSA{Hash: sha256HashingAlgorithm, Key: key, PublicKey: publicKey}
+}
+
+// RSASHA512 returns the RSA-SHA512 signature method.
+func RSASHA512(key gocrypto.Signer, publicKey *rsa.PublicKey) RSA {
+	return RSA{Hash: sha512HashingAlgorithm, Key: key, PublicKey: publicKey}
+}
+
+// RSAPSSSHA256 returns the RSA-PSS-SHA256 signature method with the given
+// salt length.
+func RSAPSSSHA256(key gocrypto.Signer, publicKey *rsa.PublicKey, saltLength int) RSA {
+	return RSA{Hash: sha256HashingAlgorithm, PSS: true, SaltLength: saltLength, Key: key, PublicKey: publicKey}
+}
+
+// Name returns "RSA-<Hash>", or "RSA-PSS-<Hash>".
+func (m RSA) Name() string {
+	if m.PSS {
+		return "RSA-PSS-" + m.HashAlgorithm()
+	}
+	return "RSA-" + m.HashAlgorithm()
+}
+
+// HashAlgorithm returns Hash, or "SHA256".
+func (m RSA) HashAlgorithm() string {
+	return hashOrDefault(m.Hash)
+}
+
+// Sign signs the signature base string with Key.
+func (m RSA) Sign(ctx context.Context, sbs string) (string, error) {
+	if m.Key == nil {
+		return "", fmt.Errorf("oauth: %v requires a signing key", m.Name())
+	}
+	if !m.PSS {
+		return signSignatureBaseString(ctx, sbs, m.Key, m.HashAlgorithm())
+	}
+	signature, err := crypto.SignPSSWithAlgorithm(ctx, []byte(sbs), m.Key, m.HashAlgorithm(), m.saltLength())
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+func (m RSA) withKeys(key gocrypto.Signer, publicKey *rsa.PublicKey) SignatureMethod {
+	if m.Key == nil {
+		m.Key = key
+	}
+	if m.PublicKey == nil {
+		m.PublicKey = publicKey
+	}
+	return m
+}
+
+// Verify verifies the signature with PublicKey, or the public key of Key.
+func (m RSA) Verify(sbs, signature string) error {
+	publicKey := m.PublicKey
+	if publicKey == nil && m.Key != nil {
+		publicKey, _ = m.Key.Public().(*rsa.PublicKey)
+	}
+	if publicKey == nil {
+		return fmt.Errorf("oauth: %v requires a public key", m.Name())
+	}
+	if !m.PSS {
+		return verifySignatureBaseString(sbs, signature, publicKey, m.HashAlgorithm())
+	}
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if err = crypto.VerifyPSSWithAlgorithm([]byte(sbs), decoded, publicKey, m.HashAlgorithm(), m.saltLength()); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// The saltLength maps the zero value to rsa.PSSSaltLengthEqualsHash.
+func (m RSA) saltLength() int {
+	if m.SaltLength == 0 {
+		return rsa.PSSSaltLengthEqualsHash
+	}
+	return m.SaltLength
+}
+
+// HMAC is the family of HMAC signature methods keyed with the consumer
+// secret and the token secret, as per
+// https://tools.ietf.org/html/rfc5849#section-3.4.2, and named after the
+// hash algorithm, such as HMAC-SHA1. Hash is the name of the algorithm as
+// registered with crypto.RegisterHash, SHA256 when empty.
+type HMAC struct {
+	Hash           string
+	ConsumerSecret string
+	TokenSecret    string
+}
+
+// HMACSHA1 returns the HMAC-SHA1 signature method.
+func HMACSHA1(consumerSecret, tokenSecret string) HMAC {
+	return HMAC{Hash: sha1HashingAlgorithm, ConsumerSecret: consumerSecret, TokenSecret: tokenSecret}
+}
+
+// HMACSHA256 returns the HMAC-SHA256 signature method.
+func HMACSHA256(consumerSecret, tokenSecret string) HMAC {
+	return HMAC{Hash: sha256HashingAlgorithm, ConsumerSecret: consumerSecret, TokenSecret: tokenSecret}
+}
+
+// Name returns "HMAC-<Hash>".
+func (m HMAC) Name() string {
+	return "HMAC-" + m.HashAlgorithm()
+}
+
+// HashAlgorithm returns Hash, or "SHA256".
+func (m HMAC) HashAlgorithm() string {
+	return hashOrDefault(m.Hash)
+}
+
+// Sign computes the HMAC of the signature base string.
+func (m HMAC) Sign(_ context.Context, sbs string) (string, error) {
+	sum, err := hmacSum(m.HashAlgorithm(), m.ConsumerSecret, m.TokenSecret, sbs)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
+
+func (m HMAC) withTokenSecret(tokenSecret string) SignatureMethod {
+	if m.TokenSecret == "" {
+		m.TokenSecret = tokenSecret
+	}
+	return m
+}
+
+// Verify compares the signature with the HMAC of the signature base string
+// in constant time.
+func (m HMAC) Verify(sbs, signature string) error {
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	sum, err := hmacSum(m.HashAlgorithm(), m.ConsumerSecret, m.TokenSecret, sbs)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(sum, decoded) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Plaintext is the PLAINTEXT signature method, which sends the encoded
+// consumer secret and token secret as the signature, as per
+// https://tools.ietf.org/html/rfc5849#section-3.4.4. It is refused over
+// non-HTTPS URLs. The oauth_body_hash is computed with SHA256.
+type Plaintext struct {
+	ConsumerSecret string
+	TokenSecret    string
+}
+
+// Name returns "PLAINTEXT".
+func (m Plaintext) Name() string {
+	return PlaintextMethodName
+}
+
+// HashAlgorithm returns "SHA256".
+func (m Plaintext) HashAlgorithm() string {
+	return sha256HashingAlgorithm
+}
+
+// Sign returns the encoded secrets, the signature base string is not used.
+func (m Plaintext) Sign(_ context.Context, _ string) (string, error) {
+	return string(hmacKey(m.ConsumerSecret, m.TokenSecret)), nil
+}
+
+func (m Plaintext) withTokenSecret(tokenSecret string) SignatureMethod {
+	if m.TokenSecret == "" {
+		m.TokenSecret = tokenSecret
+	}
+	return m
+}
+
+// Verify compares the signature with the encoded secrets in constant time.
+func (m Plaintext) Verify(_, signature string) error {
+	if subtle.ConstantTimeCompare(hmacKey(m.ConsumerSecret, m.TokenSecret), []byte(signature)) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// The checkSignatureMethod refuses signature methods disclosing secrets
+// over URLs that are not protected by TLS.
+func checkSignatureMethod(u *url.URL, signatureMethod SignatureMethod) error {
+	if signatureMethod.Name() == PlaintextMethodName && !strings.EqualFold(u.Scheme, "https") {
+		return ErrPlaintextRequiresHTTPS
+	}
+	return nil
+}
+
+// The hashOrDefault returns the hash algorithm name, or SHA256 when empty.
+func hashOrDefault(name string) string {
+	if name == "" {
+		return sha256HashingAlgorithm
+	}
+	return name
+}
+
+// The hmacKey concatenates the encoded consumer secret and token secret
+// as per https://tools.ietf.org/html/rfc5849#section-3.4.2
+func hmacKey(consumerSecret, tokenSecret string) []byte {
+	return []byte(percentEncode(consumerSecret) + "&" + percentEncode(tokenSecret))
+}
+
+// The hmacSum computes the HMAC of the signature base string with the
+// named hash algorithm.
+func hmacSum(algorithm, consumerSecret, tokenSecret, sbs string) ([]byte, error) {
+	hash, err := crypto.LookupHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(hash.New, hmacKey(consumerSecret, tokenSecret))
+	mac.Write([]byte(sbs))
+	return mac.Sum(nil), nil
+}
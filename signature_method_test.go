@@ -0,0 +1,252 @@
+package oauth_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The signature base string of https://tools.ietf.org/html/rfc5849#section-1.2,
+// parameterized with the signature method.
+func exampleSignatureBaseString(signatureMethod string) string {
+	return "GET&http%3A%2F%2Fphotos.example.net%2Fphotos&file%3Dvacation.jpg%26oauth_consumer_key%3Ddpf43f3p2l4k3l03" +
+		"%26oauth_nonce%3Dkllo9940pd9333jh%26oauth_signature_method%3D" + signatureMethod +
+		"%26oauth_timestamp%3D1191242096%26oauth_token%3Dnnch734d00sl2jdk%26oauth_version%3D1.0%26size%3Doriginal"
+}
+
+func TestHMACSignatureMethods(t *testing.T) {
+
+	tests := []struct {
+		method   oauth.SignatureMethod
+		expected string
+	}{
+		{oauth.HMACSHA1("kd94hf93k423kf44", "pfkkdhi9sl3r4s00"), "tR3+Ty81lMeYAr/Fid0kMTYa/WM="},
+		{oauth.HMACSHA256("kd94hf93k423kf44", "pfkkdhi9sl3r4s00"), "WVPzl1j6ZsnkIjWr7e3OZ3jkenL57KwaLFhYsroX1hg="},
+	}
+	for _, test := range tests {
+		sbs := exampleSignatureBaseString(test.method.Name())
+
+		signature, err := test.method.Sign(context.Background(), sbs)
+
+		if err != nil || signature != test.expected {
+			t.Errorf("Expected %v signature %v, got %v", test.method.Name(), test.expected, signature)
+		}
+		if err = test.method.Verify(sbs, signature); err != nil {
+			t.Errorf("Expected the %v signature to be valid, got %v", test.method.Name(), err)
+		}
+		if err = test.method.Verify(sbs+"&", signature); !errors.Is(err, oauth.ErrInvalidSignature) {
+			t.Errorf("Expected ErrInvalidSignature, got %v", err)
+		}
+	}
+}
+
+func TestHMACSignatureMethod_ShouldEncodeSecrets(t *testing.T) {
+
+	// the key is "a%26b&c%20d" hence secrets containing the separator
+	// cannot collide
+	sbs := exampleSignatureBaseString(oauth.HMACSHA1MethodName)
+	encoded, _ := oauth.HMACSHA1("a&b", "c d").Sign(context.Background(), sbs)
+	collision, _ := oauth.HMACSHA1("a", "b&c d").Sign(context.Background(), sbs)
+
+	if encoded == collision {
+		t.Errorf("Expected secrets to be percent encoded in the key")
+	}
+}
+
+func TestHttpRequestSigningWithSignatureMethod(t *testing.T) {
+
+	// GIVEN
+	method := oauth.HMACSHA256("consumer secret", "token secret")
+	signer := &oauth.Signer{ConsumerKey: consumerKey, Method: method}
+	req, _ := http.NewRequest("POST", "https://api.example.com/service?a=1", strings.NewReader("payload"))
+
+	// WHEN
+	err := signer.Sign(req)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to sign the http request, got %v", err)
+	}
+	header, _ := oauth.ParseAuthorizationHeader(req.Header.Get(oauth.AuthorizationHeaderName))
+	if header.SignatureMethod() != oauth.HMACSHA256MethodName {
+		t.Errorf("Expected HMAC-SHA256, got %v", header.SignatureMethod())
+	}
+	if err = (&oauth.Verifier{Method: method}).Verify(req); err != nil {
+		t.Errorf("Expected the request to be verified, got %v", err)
+	}
+	if err = (&oauth.Verifier{Method: oauth.HMACSHA256("consumer secret", "")}).Verify(req); !errors.Is(err, oauth.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature without the token secret, got %v", err)
+	}
+	if err = (&oauth.Verifier{PublicKey: &signingKey.PublicKey}).Verify(req); !errors.Is(err, oauth.ErrUnsupportedSignatureMethod) {
+		t.Errorf("Expected ErrUnsupportedSignatureMethod, got %v", err)
+	}
+}
+
+func TestHttpRequestSigningWithSignatureMethod_ShouldEncodeGeneratedParams(t *testing.T) {
+
+	// GIVEN
+	method := oauth.HMACSHA1("consumer secret", "")
+	signer := &oauth.Signer{
+		ConsumerKey:    "key+/=",
+		Method:         method,
+		Clock:          oauth.ClockFunc(func() time.Time { return time.Unix(1111111111, 0) }),
+		NonceGenerator: oauth.NonceFunc(func() (string, error) { return "nonce", nil }),
+	}
+	req, _ := http.NewRequest("GET", "https://api.example.com/service", nil)
+	// the SHA1 hash of an empty body is "2jmj7l5rSw0yVb/vlWAYkK/YBwk="
+	expectedSbs := "GET&https%3A%2F%2Fapi.example.com%2Fservice&oauth_body_hash%3D2jmj7l5rSw0yVb%252FvlWAYkK%252FYBwk%253D" +
+		"%26oauth_consumer_key%3Dkey%252B%252F%253D%26oauth_nonce%3Dnonce%26oauth_signature_method%3DHMAC-SHA1" +
+		"%26oauth_timestamp%3D1111111111%26oauth_version%3D1.0"
+	mac := hmac.New(sha1.New, []byte("consumer%20secret&"))
+	mac.Write([]byte(expectedSbs))
+	expectedSignature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	// WHEN
+	details, err := signer.SignWithDetails(req)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to sign the http request, got %v", err)
+	}
+	if details.SignatureBaseString != expectedSbs || details.Signature != expectedSignature {
+		t.Errorf("Expected the encoded parameters to be signed, got %v %v", details.SignatureBaseString, details.Signature)
+	}
+	authHeader := req.Header.Get(oauth.AuthorizationHeaderName)
+	if !strings.Contains(authHeader, `oauth_consumer_key="key%2B%2F%3D"`) || !strings.Contains(authHeader, `oauth_body_hash="2jmj7l5rSw0yVb%2FvlWAYkK%2FYBwk%3D"`) {
+		t.Errorf("Expected the header values to be encoded, got %v", authHeader)
+	}
+	if err = (&oauth.Verifier{Method: method}).Verify(req); err != nil {
+		t.Errorf("Expected the request to be verified, got %v", err)
+	}
+}
+
+func TestRSASHA256SignatureMethod(t *testing.T) {
+
+	u, _ := url.Parse("https://sandbox.api.mastercard.com/service")
+	authHeader, err := oauth.GetAuthorizationHeaderWithMethod(context.Background(), u, "GET", nil, consumerKey, oauth.RSASHA256(signingKey, nil))
+	if err != nil {
+		t.Fatalf("Expected the authorization header, got %v", err)
+	}
+	if err = oauth.VerifyAuthorizationHeaderWithMethod(authHeader, u, "GET", nil, oauth.RSASHA256(signingKey, nil)); err != nil {
+		t.Errorf("Expected the header to be verified with the public key of the signing key, got %v", err)
+	}
+	if _, err = oauth.RSASHA256(nil, nil).Sign(context.Background(), "sbs"); err == nil {
+		t.Errorf("Expected an error in case of missing signing key")
+	}
+	if err = oauth.RSASHA256(nil, nil).Verify("sbs", "signature"); err == nil {
+		t.Errorf("Expected an error in case of missing public key")
+	}
+}
+
+func TestRSASignatureMethods_ShouldFollowHashAlgorithm(t *testing.T) {
+
+	u, _ := url.Parse("https://sandbox.api.mastercard.com/service")
+	tests := []struct {
+		method         oauth.SignatureMethod
+		bodyHashLength int
+	}{
+		{oauth.RSASHA1(signingKey, nil), 28},
+		{oauth.RSASHA256(signingKey, nil), 44},
+		{oauth.RSASHA512(signingKey, nil), 88},
+		{oauth.RSA{Key: signingKey}, 44},
+		{oauth.HMACSHA1("secret", ""), 28},
+		{oauth.HMAC{Hash: "SHA512", ConsumerSecret: "secret"}, 88},
+	}
+	for _, test := range tests {
+		authHeader, err := oauth.GetAuthorizationHeaderWithMethod(context.Background(), u, "POST", []byte("payload"), consumerKey, test.method)
+		if err != nil {
+			t.Fatalf("Expected the %v authorization header, got %v", test.method.Name(), err)
+		}
+		header, _ := oauth.ParseAuthorizationHeader(authHeader)
+		if header.SignatureMethod() != test.method.Name() || len(header.BodyHash()) != test.bodyHashLength {
+			t.Errorf("Expected the %v method and body hash, got %v", test.method.Name(), authHeader)
+		}
+		if err = oauth.VerifyAuthorizationHeaderWithMethod(authHeader, u, "POST", []byte("payload"), test.method); err != nil {
+			t.Errorf("Expected the %v header to be verified, got %v", test.method.Name(), err)
+		}
+	}
+
+	if name := (oauth.RSA{}).Name(); name != oauth.RSASHA256MethodName {
+		t.Errorf("Expected %v, got %v", oauth.RSASHA256MethodName, name)
+	}
+	if name := oauth.RSAPSSSHA256(nil, nil, 0).Name(); name != oauth.RSAPSSSHA256MethodName {
+		t.Errorf("Expected %v, got %v", oauth.RSAPSSSHA256MethodName, name)
+	}
+
+	// a SHA1 signature does not verify as SHA512
+	authHeader, _ := oauth.GetAuthorizationHeaderWithMethod(context.Background(), u, "GET", nil, consumerKey, oauth.RSASHA1(signingKey, nil))
+	tampered := strings.Replace(authHeader, oauth.RSASHA1MethodName, oauth.RSASHA512MethodName, 1)
+	if err := oauth.VerifyAuthorizationHeaderWithMethod(tampered, u, "GET", nil, oauth.RSASHA512(signingKey, nil)); err == nil {
+		t.Errorf("Expected the tampered header to be rejected")
+	}
+}
+
+func TestPlaintextSignatureMethod(t *testing.T) {
+
+	// GIVEN
+	method := oauth.Plaintext{ConsumerSecret: "kd94hf93k423kf44", TokenSecret: "pfk&kdhi9"}
+	u, _ := url.Parse("https://photos.example.net/photos")
+
+	// WHEN
+	authHeader, err := oauth.GetAuthorizationHeaderWithMethod(context.Background(), u, "GET", nil, consumerKey, method)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the authorization header, got %v", err)
+	}
+	header, _ := oauth.ParseAuthorizationHeader(authHeader)
+	if header.Signature() != "kd94hf93k423kf44&pfk%26kdhi9" {
+		t.Errorf("Expected the encoded secrets as signature, got %v", header.Signature())
+	}
+	if err = oauth.VerifyAuthorizationHeaderWithMethod(authHeader, u, "GET", nil, method); err != nil {
+		t.Errorf("Expected the header to be verified, got %v", err)
+	}
+	if err = oauth.VerifyAuthorizationHeaderWithMethod(authHeader, u, "GET", nil, oauth.Plaintext{ConsumerSecret: "kd94hf93k423kf44"}); !errors.Is(err, oauth.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+
+	// PLAINTEXT is refused without TLS
+	insecure, _ := url.Parse("http://photos.example.net/photos")
+	if _, err = oauth.GetAuthorizationHeaderWithMethod(context.Background(), insecure, "GET", nil, consumerKey, method); !errors.Is(err, oauth.ErrPlaintextRequiresHTTPS) {
+		t.Errorf("Expected ErrPlaintextRequiresHTTPS, got %v", err)
+	}
+	if err = oauth.VerifyAuthorizationHeaderWithMethod(authHeader, insecure, "GET", nil, method); !errors.Is(err, oauth.ErrPlaintextRequiresHTTPS) {
+		t.Errorf("Expected ErrPlaintextRequiresHTTPS, got %v", err)
+	}
+}
+
+func TestHttpRequestSigningWithRSAPSS(t *testing.T) {
+
+	// GIVEN
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Method: oauth.RSAPSSSHA256(nil, nil, 32)}
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", strings.NewReader("payload"))
+
+	// WHEN
+	err := signer.Sign(req)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to sign the http request, got %v", err)
+	}
+	header, _ := oauth.ParseAuthorizationHeader(req.Header.Get(oauth.AuthorizationHeaderName))
+	if header.SignatureMethod() != oauth.RSAPSSSHA256MethodName {
+		t.Errorf("Expected RSA-PSS-SHA256, got %v", header.SignatureMethod())
+	}
+	if err = (&oauth.Verifier{PublicKey: &signingKey.PublicKey, Method: oauth.RSAPSSSHA256(nil, nil, 32)}).Verify(req); err != nil {
+		t.Errorf("Expected the request to be verified, got %v", err)
+	}
+	if err = (&oauth.Verifier{PublicKey: &signingKey.PublicKey, Method: oauth.RSAPSSSHA256(nil, nil, 20)}).Verify(req); !errors.Is(err, oauth.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature with another salt length, got %v", err)
+	}
+	if err = (&oauth.Verifier{PublicKey: &signingKey.PublicKey}).Verify(req); !errors.Is(err, oauth.ErrUnsupportedSignatureMethod) {
+		t.Errorf("Expected ErrUnsupportedSignatureMethod, got %v", err)
+	}
+}
@@ -2,41 +2,253 @@ package oauth
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/rsa"
 	"errors"
 	"io"
+	"mime"
 	"net/http"
 )
 
+// CredentialsProvider supplies the consumer key and the signing key on
+// every request, which allows keys to be rotated while the Signer is in use.
+// Implementations must be safe for concurrent use.
+type CredentialsProvider interface {
+	Credentials() (consumerKey string, key crypto.Signer, err error)
+}
+
 // Signer represents the http request signer that holds the
 // consumer key and the signing key.
 type Signer struct {
 	ConsumerKey string
 	SigningKey  *rsa.PrivateKey
+	// Key can be used in place of SigningKey for RSA keys that cannot be
+	// loaded in memory, such as keys held in a HSM or a cloud KMS. Key
+	// takes precedence over SigningKey.
+	Key crypto.Signer
+	// Credentials, when set, supplies the consumer key and the signing
+	// key of every request in place of ConsumerKey, SigningKey and Key.
+	Credentials CredentialsProvider
+	// Method, when set, signs requests in place of the RSA-SHA256 method
+	// using the signing key, for instance HMACSHA1 with the consumer
+	// and token secrets. RSA methods without a key, such as
+	// RSAPSSSHA256(nil, nil, 32), use the signing key of the Signer.
+	Method SignatureMethod
+	// Token is the oauth_token of the token credentials requests are
+	// made on behalf of, if any.
+	Token string
+	// TokenSecret keys the HMAC and PLAINTEXT methods that have no
+	// token secret of their own.
+	TokenSecret string
+	// HashFormBody keeps hashing application/x-www-form-urlencoded bodies
+	// into oauth_body_hash instead of signing their parameters, for
+	// receivers expecting the behaviour of previous versions.
+	HashFormBody bool
+	// Transmission selects where the signed oauth parameters are written,
+	// the Authorization header by default. The FormBody transmission
+	// requires a form-encoded body or no body at all.
+	Transmission ParameterTransmission
+	// Clock, when set, supplies the time of oauth_timestamp in place of
+	// the system clock.
+	Clock Clock
+	// NonceGenerator, when set, supplies oauth_nonce. Otherwise nonces
+	// are generated from Rand.
+	NonceGenerator NonceGenerator
+	// Rand is the source of the random bytes of generated nonces,
+	// crypto/rand.Reader when nil. It must be safe for concurrent use,
+	// as concurrent requests read from it.
+	Rand io.Reader
 }
 
 // Sign signs the http request. It generates the authorization header and sets
-// on the header of provided http request.
+// on the header of provided http request, or writes the oauth parameters in
+// the query or the body depending on Transmission.
 func (signer *Signer) Sign(req *http.Request) error {
-	if signer.ConsumerKey == "" {
-		return errors.New("signer: provide valid consumer key")
+	_, err := signer.SignWithDetails(req)
+	return err
+}
+
+// SignWithDetails signs the http request like Sign and returns the details
+// of the signature, such as the signature base string, for debugging.
+func (signer *Signer) SignWithDetails(req *http.Request) (*SignatureDetails, error) {
+	consumerKey, key, err := signer.getCredentials()
+	if err != nil {
+		return nil, err
 	}
-	if signer.SigningKey == nil {
-		return errors.New("signer: provide valid signing key")
+	if consumerKey == "" {
+		return nil, errors.New("signer: provide valid consumer key")
 	}
+	signatureMethod := signer.Method
+	if signatureMethod == nil {
+		if key == nil {
+			return nil, errors.New("signer: provide valid signing key")
+		}
+		signatureMethod = RSASHA256(key, nil)
+	} else if keyed, ok := signatureMethod.(keyedSignatureMethod); ok && key != nil {
+		signatureMethod = keyed.withKeys(key, nil)
+	}
+	signatureMethod = withTokenSecret(signatureMethod, signer.TokenSecret)
 	if req == nil {
-		return errors.New("signer: Nil http.Request provided")
+		return nil, errors.New("signer: Nil http.Request provided")
 	}
-	body, err := getRequestBody(req)
+	if signer.Transmission == FormBody {
+		return signer.signFormBody(req, consumerKey, signatureMethod)
+	}
+	bodyHash, formParams, err := getRequestBodyParams(req, signatureMethod.HashAlgorithm(), signer.HashFormBody)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	authHeader, err := GetAuthorizationHeader(req.URL, req.Method, body, signer.ConsumerKey, signer.SigningKey)
+	u := req.URL
+	if signer.Transmission == QueryString {
+		stripped := *req.URL
+		stripped.RawQuery = removeOAuthParams(stripped.RawQuery, signer.params())
+		u = &stripped
+	}
+	oauthParams, details, err := getSignedOAuthParams(req.Context(), u, req.Method, bodyHash, formParams, consumerKey, signatureMethod, signer.params(), signer.headerOptions())
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if signer.Transmission == QueryString {
+		req.URL.RawQuery = appendParams(u.RawQuery, encodeOAuthParams(oauthParams))
+		return details, nil
+	}
+	req.Header.Set(AuthorizationHeaderName, getAuthorizationString(oauthParams))
+	return details, nil
+}
+
+// The signFormBody signs the http request and appends the oauth parameters
+// to its form-encoded body, in place of the ones of a previous signature.
+// Requests without body get a form body.
+func (signer *Signer) signFormBody(req *http.Request, consumerKey string, signatureMethod SignatureMethod) (*SignatureDetails, error) {
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if signer.HashFormBody || hasBody && !isFormRequest(req) {
+		return nil, ErrFormBodyRequired
+	}
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	// the parameters of a previous signature are replaced
+	body = []byte(removeOAuthParams(string(body), signer.params()))
+	formParams, err := extractFormParams(body)
+	if err != nil {
+		return nil, err
+	}
+	oauthParams, details, err := getSignedOAuthParams(req.Context(), req.URL, req.Method, "", formParams, consumerKey, signatureMethod, signer.params(), signer.headerOptions())
+	if err != nil {
+		return nil, err
+	}
+	setFormBody(req, body, oauthParams)
+	return details, nil
+}
+
+// The headerOptions returns the sources of the nonce and the timestamp.
+func (signer *Signer) headerOptions() *headerOptions {
+	nonceGenerator := signer.NonceGenerator
+	if nonceGenerator == nil && signer.Rand != nil {
+		nonceGenerator = RandomNonceGenerator{Rand: signer.Rand}
+	}
+	return &headerOptions{clock: signer.Clock, nonceGenerator: nonceGenerator}
+}
+
+// The params returns the additional oauth parameters of the signer.
+func (signer *Signer) params() map[string]string {
+	return map[string]string{oauthTokenParam: signer.Token}
+}
+
+// The getCredentials returns the consumer key and the crypto.Signer to sign
+// with. The key is nil when no key has been provided.
+func (signer *Signer) getCredentials() (string, crypto.Signer, error) {
+	if signer.Credentials != nil {
+		return signer.Credentials.Credentials()
+	}
+	if signer.Key != nil {
+		return signer.ConsumerKey, signer.Key, nil
+	}
+	if signer.SigningKey != nil {
+		return signer.ConsumerKey, signer.SigningKey, nil
+	}
+	return signer.ConsumerKey, nil, nil
+}
+
+// The getRequestBodyParams returns the body hash of the given http request
+// or, for form-encoded bodies, the encoded form parameters to sign. Form
+// bodies are hashed like any other body when hashFormBody is set.
+func getRequestBodyParams(req *http.Request, algorithm string, hashFormBody bool) (string, map[string][]string, error) {
+	if hashFormBody || !isFormRequest(req) {
+		bodyHash, err := getRequestBodyHash(req, algorithm)
+		return bodyHash, nil, err
+	}
+	body, err := readRequestBody(req)
+	if err != nil {
+		return "", nil, err
+	}
+	formParams, err := extractFormParams(body)
+	if err != nil {
+		return "", nil, err
+	}
+	return "", formParams, nil
+}
+
+// The isFormRequest reports whether the body of the given http request is
+// form-encoded as per https://tools.ietf.org/html/rfc5849#section-3.4.1.3.1
+func isFormRequest(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == formContentType
+}
+
+// The getRequestBodyHash computes the body hash of the given http request
+// without buffering the body when possible. The body is hashed from a copy
+// obtained through req.GetBody, or read from an io.Seeker (such as an
+// *os.File) and rewound. One-shot streams are buffered.
+func getRequestBodyHash(req *http.Request, algorithm string) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return getBodyHash(nil, algorithm)
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		return getBodyHashFromReader(body, algorithm)
+	}
+	if seeker, ok := req.Body.(io.ReadSeeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			bodyHash, err := getBodyHashFromReader(seeker, algorithm)
+			if err != nil {
+				return "", err
+			}
+			if _, err = seeker.Seek(offset, io.SeekStart); err != nil {
+				return "", err
+			}
+			return bodyHash, nil
+		}
+	}
+	body, err := getRequestBody(req)
+	if err != nil {
+		return "", err
+	}
+	return getBodyHash(body, algorithm)
+}
+
+// The readRequestBody returns the body content of the given http request
+// while leaving the body readable, from a copy obtained through req.GetBody
+// when possible.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
 	}
-	req.Header.Set(AuthorizationHeaderName, authHeader)
-	return nil
+	return getRequestBody(req)
 }
 
 // The getRequestBody extracts the body content from the given
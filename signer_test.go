@@ -2,11 +2,21 @@ package oauth_test
 
 import (
 	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	oauth "github.com/mastercard/oauth1-signer-go"
 	"github.com/mastercard/oauth1-signer-go/utils"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -80,3 +90,198 @@ func TestHttpRequestSigningWithInvalidInput(t *testing.T) {
 		t.Errorf("Expected the authorization header, got %v", authorizationVal)
 	}
 }
+
+// opaqueSigner hides the *rsa.PrivateKey behind the crypto.Signer interface,
+// the same way HSM or KMS backed keys do
+type opaqueSigner struct {
+	crypto.Signer
+	ctx context.Context
+}
+
+func (s *opaqueSigner) SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.ctx = ctx
+	return s.Signer.Sign(rand, digest, opts)
+}
+
+func TestHttpRequestSigningWithCryptoSigner(t *testing.T) {
+	key := &opaqueSigner{Signer: signingKey}
+	signer := &oauth.Signer{
+		ConsumerKey: consumerKey,
+		Key:         key,
+	}
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", bytes.NewBuffer(jsonValue))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	err := signer.Sign(req)
+	if err != nil {
+		t.Errorf("Expected to sign the http request, got %v", err)
+	}
+	if key.ctx != ctx {
+		t.Errorf("Expected the request context to be passed to the signer")
+	}
+	verifier := &oauth.Verifier{PublicKey: &signingKey.PublicKey}
+	if err := verifier.Verify(req); err != nil {
+		t.Errorf("Expected a valid signature, got %v", err)
+	}
+}
+
+type staticCredentials struct {
+	consumerKey string
+	key         crypto.Signer
+	err         error
+}
+
+func (c staticCredentials) Credentials() (string, crypto.Signer, error) {
+	return c.consumerKey, c.key, c.err
+}
+
+func TestHttpRequestSigningWithCredentialsProvider(t *testing.T) {
+
+	signer := &oauth.Signer{ConsumerKey: "ignored", Credentials: staticCredentials{consumerKey, signingKey, nil}}
+	req, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service", nil)
+	if err := signer.Sign(req); err != nil {
+		t.Fatalf("Expected to sign the http request, got %v", err)
+	}
+	header, _ := oauth.ParseAuthorizationHeader(req.Header.Get(oauth.AuthorizationHeaderName))
+	if header.ConsumerKey() != consumerKey {
+		t.Errorf("Expected the provided consumer key, got %v", header.ConsumerKey())
+	}
+
+	signer.Credentials = staticCredentials{err: io.ErrUnexpectedEOF}
+	if err := signer.Sign(req); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected the provider error, got %v", err)
+	}
+}
+
+func TestHttpRequestSigning_ShouldHashBodyWithoutConsumingIt(t *testing.T) {
+
+	// GIVEN
+	payload := strings.Repeat("0123456789", 10000)
+	hash := sha256.Sum256([]byte(payload))
+	expected := base64.StdEncoding.EncodeToString(hash[:])
+
+	file, _ := os.CreateTemp(t.TempDir(), "payload")
+	_, _ = file.WriteString("ignored" + payload)
+	_, _ = file.Seek(int64(len("ignored")), io.SeekStart)
+	defer file.Close()
+
+	withGetBody, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", strings.NewReader(payload))
+	withFile, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", file)
+	oneShot, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", nil)
+	oneShot.Body = io.NopCloser(strings.NewReader(payload))
+
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}
+	for _, req := range []*http.Request{withGetBody, withFile, oneShot} {
+
+		// WHEN
+		err := signer.Sign(req)
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected to sign the http request, got %v", err)
+		}
+		header, _ := oauth.ParseAuthorizationHeader(req.Header.Get(oauth.AuthorizationHeaderName))
+		if header.BodyHash() != expected {
+			t.Errorf("Expected body hash %v, got %v", expected, header.BodyHash())
+		}
+		if body, _ := io.ReadAll(req.Body); string(body) != payload {
+			t.Errorf("Expected the body to remain readable, got %d bytes", len(body))
+		}
+	}
+}
+
+func TestHttpRequestSigning_ShouldSignFormParameters(t *testing.T) {
+
+	// GIVEN
+	form := "amount=10.00&note=caf%C3%A9+au+lait"
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service?a=1", strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}
+	verifier := &oauth.Verifier{PublicKey: &signingKey.PublicKey}
+
+	// WHEN
+	err := signer.Sign(req)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to sign the http request, got %v", err)
+	}
+	header, _ := oauth.ParseAuthorizationHeader(req.Header.Get(oauth.AuthorizationHeaderName))
+	if _, ok := header.Lookup("oauth_body_hash"); ok {
+		t.Errorf("Expected no body hash for a form-encoded body")
+	}
+	if err = verifier.Verify(req); err != nil {
+		t.Errorf("Expected the request to be verified, got %v", err)
+	}
+	tampered := req.Clone(req.Context())
+	tampered.Body = io.NopCloser(strings.NewReader("amount=99.00&note=caf%C3%A9+au+lait"))
+	tampered.GetBody = nil
+	if err = verifier.Verify(tampered); err == nil {
+		t.Errorf("Expected the tampered form to be rejected")
+	}
+	if err = (&oauth.Verifier{PublicKey: &signingKey.PublicKey, HashFormBody: true}).Verify(req); err == nil {
+		t.Errorf("Expected the request to be rejected without body hash")
+	}
+}
+
+func TestHttpRequestSigning_ShouldHashFormBody_WhenHashFormBodySet(t *testing.T) {
+
+	// GIVEN
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", strings.NewReader("amount=10.00"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, HashFormBody: true}
+
+	// WHEN
+	err := signer.Sign(req)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to sign the http request, got %v", err)
+	}
+	header, _ := oauth.ParseAuthorizationHeader(req.Header.Get(oauth.AuthorizationHeaderName))
+	if header.BodyHash() == "" {
+		t.Errorf("Expected the body hash to be kept")
+	}
+	if err = (&oauth.Verifier{PublicKey: &signingKey.PublicKey, HashFormBody: true}).Verify(req); err != nil {
+		t.Errorf("Expected the request to be verified, got %v", err)
+	}
+	if err = (&oauth.Verifier{PublicKey: &signingKey.PublicKey}).Verify(req); !errors.Is(err, oauth.ErrBodyHashMismatch) {
+		t.Errorf("Expected ErrBodyHashMismatch, got %v", err)
+	}
+}
+
+func TestHttpRequestSigning_ShouldReturnSignatureDetails(t *testing.T) {
+
+	// GIVEN
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com:443/service?b=2&a=1", strings.NewReader("payload"))
+	signer := &oauth.Signer{
+		ConsumerKey:    consumerKey,
+		SigningKey:     signingKey,
+		Clock:          oauth.ClockFunc(func() time.Time { return time.Unix(1111111111, 0) }),
+		NonceGenerator: oauth.NonceFunc(func() (string, error) { return "nonce", nil }),
+	}
+	hash := sha256.Sum256([]byte("payload"))
+	bodyHash := base64.StdEncoding.EncodeToString(hash[:])
+
+	// WHEN
+	details, err := signer.SignWithDetails(req)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to sign the http request, got %v", err)
+	}
+	expected := "a=1&b=2&oauth_body_hash=" + url.QueryEscape(bodyHash) + "&oauth_consumer_key=" + url.QueryEscape(consumerKey) +
+		"&oauth_nonce=nonce&oauth_signature_method=RSA-SHA256&oauth_timestamp=1111111111&oauth_version=1.0"
+	if details.BodyHash != bodyHash || details.BaseURL != "https://sandbox.api.mastercard.com/service" || details.ParameterString != expected {
+		t.Errorf("Expected the normalized request, got %v %v %v", details.BodyHash, details.BaseURL, details.ParameterString)
+	}
+	if !strings.HasPrefix(details.SignatureBaseString, "POST&https%3A%2F%2Fsandbox.api.mastercard.com%2Fservice&a%3D1%26b%3D2%26oauth_body_hash%3D") {
+		t.Errorf("Expected the signature base string, got %v", details.SignatureBaseString)
+	}
+	header, _ := oauth.ParseAuthorizationHeader(req.Header.Get(oauth.AuthorizationHeaderName))
+	if header.Signature() != details.Signature {
+		t.Errorf("Expected the signature of the header, got %v", details.Signature)
+	}
+}
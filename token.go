@@ -0,0 +1,158 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const (
+	oauthTokenSecretParam       = "oauth_token_secret"
+	oauthCallbackConfirmedParam = "oauth_callback_confirmed"
+	// OutOfBandCallback is the oauth_callback of clients that cannot
+	// receive callbacks, as per https://tools.ietf.org/html/rfc5849#section-2.1
+	OutOfBandCallback    = "oob"
+	maxTokenResponseSize = 1 << 16
+)
+
+// ErrCallbackNotConfirmed is returned when the server does not confirm the
+// callback of the temporary credentials request.
+var ErrCallbackNotConfirmed = errors.New("oauth: callback not confirmed")
+
+// Token holds temporary credentials or token credentials, as per
+// https://tools.ietf.org/html/rfc5849#section-1.1
+type Token struct {
+	Token  string
+	Secret string
+	// Params holds the other parameters returned by the server.
+	Params url.Values
+}
+
+// TokenError is returned when the server rejects a credentials request.
+type TokenError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("oauth: credentials request failed with status %v: %v", e.StatusCode, e.Body)
+}
+
+// TokenClient obtains token credentials through the redirection-based
+// authorization of https://tools.ietf.org/html/rfc5849#section-2:
+// RequestTemporaryCredentials, then the resource owner is sent to
+// AuthorizationURL and RequestToken exchanges the verifier for token
+// credentials.
+type TokenClient struct {
+	ConsumerKey string
+	// Method signs the credentials requests, for instance HMACSHA1 with
+	// the consumer secret. The token secret is set by TokenClient.
+	Method                  SignatureMethod
+	TemporaryCredentialsURL string
+	ResourceOwnerAuthURL    string
+	TokenRequestURL         string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// RequestTemporaryCredentials obtains temporary credentials as per
+// https://tools.ietf.org/html/rfc5849#section-2.1. The callback defaults
+// to OutOfBandCallback.
+func (c *TokenClient) RequestTemporaryCredentials(ctx context.Context, callback string) (*Token, error) {
+	if callback == "" {
+		callback = OutOfBandCallback
+	}
+	token, err := c.requestCredentials(ctx, c.TemporaryCredentialsURL, "", map[string]string{oauthCallbackParam: callback})
+	if err != nil {
+		return nil, err
+	}
+	if token.Params.Get(oauthCallbackConfirmedParam) != "true" {
+		return nil, ErrCallbackNotConfirmed
+	}
+	return token, nil
+}
+
+// AuthorizationURL returns the URL the resource owner must be sent to in
+// order to authorize the temporary credentials, as per
+// https://tools.ietf.org/html/rfc5849#section-2.2
+func (c *TokenClient) AuthorizationURL(temporary *Token) (string, error) {
+	u, err := url.Parse(c.ResourceOwnerAuthURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set(oauthTokenParam, temporary.Token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// RequestToken exchanges the authorized temporary credentials and the
+// verifier for token credentials, as per https://tools.ietf.org/html/rfc5849#section-2.3
+func (c *TokenClient) RequestToken(ctx context.Context, temporary *Token, verifier string) (*Token, error) {
+	params := map[string]string{
+		oauthTokenParam:    temporary.Token,
+		oauthVerifierParam: verifier,
+	}
+	return c.requestCredentials(ctx, c.TokenRequestURL, temporary.Secret, params)
+}
+
+// Signer returns a Signer making requests on behalf of the resource owner
+// with the token credentials.
+func (c *TokenClient) Signer(token *Token) *Signer {
+	return &Signer{ConsumerKey: c.ConsumerKey, Method: c.Method, Token: token.Token, TokenSecret: token.Secret}
+}
+
+// The requestCredentials sends a signed POST request and parses the
+// form-encoded credentials of the response.
+func (c *TokenClient) requestCredentials(ctx context.Context, rawURL, tokenSecret string, params map[string]string) (*Token, error) {
+	if c.ConsumerKey == "" || c.Method == nil {
+		return nil, errors.New("oauth: provide valid consumer key and signature method")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	signatureMethod := withTokenSecret(c.Method, tokenSecret)
+	authHeader, err := GetAuthorizationHeaderWithParams(ctx, u, http.MethodPost, nil, c.ConsumerKey, signatureMethod, params)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(AuthorizationHeaderName, authHeader)
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxTokenResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &TokenError{StatusCode: res.StatusCode, Body: string(body)}
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	token := &Token{Token: values.Get(oauthTokenParam), Secret: values.Get(oauthTokenSecretParam)}
+	if token.Token == "" {
+		return nil, fmt.Errorf("oauth: %v missing from the response", oauthTokenParam)
+	}
+	values.Del(oauthTokenParam)
+	values.Del(oauthTokenSecretParam)
+	token.Params = values
+	return token, nil
+}
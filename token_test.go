@@ -0,0 +1,160 @@
+package oauth_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+const (
+	consumerSecret  = "kd94hf93k423kf44"
+	temporaryToken  = "hh5s93j4hdidpola"
+	temporarySecret = "hdhd0244k9j7ao03"
+	verifierCode    = "hfdp7dh39dks9884"
+	accessToken     = "nnch734d00sl2jdk"
+	accessSecret    = "pfkkdhi9sl3r4s00"
+	callback        = "https://printer.example.com/ready?x=1"
+)
+
+// The newProvider returns a provider implementing the three-legged flow of
+// https://tools.ietf.org/html/rfc5849#section-1.2 with HMAC-SHA1.
+func newProvider(t *testing.T, confirmCallback bool) *httptest.Server {
+	verify := func(w http.ResponseWriter, r *http.Request, tokenSecret string) *oauth.OAuthParams {
+		verifier := &oauth.Verifier{Method: oauth.HMACSHA1(consumerSecret, tokenSecret)}
+		if err := verifier.Verify(r); err != nil {
+			t.Errorf("Expected the %v request to be verified, got %v", r.URL.Path, err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return nil
+		}
+		params, _ := oauth.ParseAuthorizationHeader(r.Header.Get(oauth.AuthorizationHeaderName))
+		return params
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/initiate", func(w http.ResponseWriter, r *http.Request) {
+		if params := verify(w, r, ""); params != nil {
+			if cb := params.Callback(); cb != callback {
+				t.Errorf("Expected the callback, got %v", cb)
+			}
+			_, _ = fmt.Fprintf(w, "oauth_token=%v&oauth_token_secret=%v&oauth_callback_confirmed=%v", temporaryToken, temporarySecret, confirmCallback)
+		}
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if params := verify(w, r, temporarySecret); params != nil {
+			if params.Token() != temporaryToken || params.Verifier() != verifierCode {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = fmt.Fprint(w, "oauth_problem=token_rejected")
+				return
+			}
+			_, _ = fmt.Fprintf(w, "oauth_token=%v&oauth_token_secret=%v&user_id=42", accessToken, accessSecret)
+		}
+	})
+	mux.HandleFunc("/photos", func(w http.ResponseWriter, r *http.Request) {
+		if params := verify(w, r, accessSecret); params != nil {
+			if token := params.Token(); token != accessToken {
+				t.Errorf("Expected the access token, got %v", token)
+			}
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTokenClient(server *httptest.Server) *oauth.TokenClient {
+	return &oauth.TokenClient{
+		ConsumerKey:             "dpf43f3p2l4k3l03",
+		Method:                  oauth.HMACSHA1(consumerSecret, ""),
+		TemporaryCredentialsURL: server.URL + "/initiate",
+		ResourceOwnerAuthURL:    server.URL + "/authorize?lang=en",
+		TokenRequestURL:         server.URL + "/token",
+		HTTPClient:              server.Client(),
+	}
+}
+
+func TestTokenClient_ShouldCompleteThreeLeggedFlow(t *testing.T) {
+
+	// GIVEN
+	server := newProvider(t, true)
+	defer server.Close()
+	client := newTokenClient(server)
+
+	// WHEN
+	temporary, err := client.RequestTemporaryCredentials(context.Background(), callback)
+	if err != nil {
+		t.Fatalf("Expected temporary credentials, got %v", err)
+	}
+	authURL, _ := client.AuthorizationURL(temporary)
+	token, err := client.RequestToken(context.Background(), temporary, verifierCode)
+	if err != nil {
+		t.Fatalf("Expected token credentials, got %v", err)
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/photos?file=vacation.jpg&size=original", nil)
+	err = client.Signer(token).Sign(req)
+	if err != nil {
+		t.Fatalf("Expected to sign the request, got %v", err)
+	}
+	res, err := server.Client().Do(req)
+
+	// THEN
+	if temporary.Token != temporaryToken || temporary.Secret != temporarySecret {
+		t.Errorf("Expected the temporary credentials, got %+v", temporary)
+	}
+	if u, _ := url.Parse(authURL); u.Query().Get("oauth_token") != temporaryToken || u.Query().Get("lang") != "en" {
+		t.Errorf("Expected the authorization URL to carry the temporary token, got %v", authURL)
+	}
+	if token.Token != accessToken || token.Secret != accessSecret || token.Params.Get("user_id") != "42" {
+		t.Errorf("Expected the token credentials, got %+v", token)
+	}
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Errorf("Expected the protected resource to be accessed, got %v", err)
+	}
+}
+
+func TestTokenClientInvalidInput(t *testing.T) {
+
+	server := newProvider(t, false)
+	defer server.Close()
+	client := newTokenClient(server)
+
+	if _, err := client.RequestTemporaryCredentials(context.Background(), callback); !errors.Is(err, oauth.ErrCallbackNotConfirmed) {
+		t.Errorf("Expected ErrCallbackNotConfirmed, got %v", err)
+	}
+
+	var tokenErr *oauth.TokenError
+	temporary := &oauth.Token{Token: temporaryToken, Secret: temporarySecret}
+	if _, err := client.RequestToken(context.Background(), temporary, "wrong verifier"); !errors.As(err, &tokenErr) || tokenErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a TokenError, got %v", err)
+	}
+
+	if _, err := (&oauth.TokenClient{}).RequestTemporaryCredentials(context.Background(), ""); err == nil {
+		t.Errorf("Expected an error in case of missing consumer key")
+	}
+}
+
+func TestGetAuthorizationHeaderWithParams(t *testing.T) {
+
+	u, _ := url.Parse("https://sandbox.api.mastercard.com/service")
+	method := oauth.HMACSHA1(consumerSecret, "")
+
+	authHeader, err := oauth.GetAuthorizationHeaderWithParams(context.Background(), u, "GET", nil, consumerKey, method, map[string]string{"oauth_callback": callback, "oauth_token": ""})
+	if err != nil {
+		t.Fatalf("Expected the authorization header, got %v", err)
+	}
+	params, _ := oauth.ParseAuthorizationHeader(authHeader)
+	if cb := params.Callback(); cb != callback {
+		t.Errorf("Expected the callback, got %v", cb)
+	}
+	if _, ok := params.Lookup("oauth_token"); ok {
+		t.Errorf("Expected empty parameters to be omitted")
+	}
+	if err = oauth.VerifyAuthorizationHeaderWithMethod(authHeader, u, "GET", nil, method); err != nil {
+		t.Errorf("Expected the header to be verified, got %v", err)
+	}
+
+	if _, err = oauth.GetAuthorizationHeaderWithParams(context.Background(), u, "GET", nil, consumerKey, method, map[string]string{"realm": "x"}); err == nil {
+		t.Errorf("Expected an error in case of non-oauth parameter")
+	}
+}
@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ParameterTransmission selects where a Signer writes the signed oauth
+// parameters, as per https://tools.ietf.org/html/rfc5849#section-3.5
+type ParameterTransmission int
+
+const (
+	// AuthorizationHeader transmits the parameters in the Authorization
+	// header as per https://tools.ietf.org/html/rfc5849#section-3.5.1
+	AuthorizationHeader ParameterTransmission = iota
+	// FormBody appends the parameters to an application/x-www-form-urlencoded
+	// body as per https://tools.ietf.org/html/rfc5849#section-3.5.2
+	FormBody
+	// QueryString appends the parameters to the query of the request URI
+	// as per https://tools.ietf.org/html/rfc5849#section-3.5.3
+	QueryString
+)
+
+// ErrFormBodyRequired is returned when the FormBody transmission is used
+// with a body that is not form-encoded, or that is hashed.
+var ErrFormBodyRequired = errors.New("signer: form body transmission requires an application/x-www-form-urlencoded body")
+
+// The encodeOAuthParams serializes the signed oauth parameters, whose values
+// are already percent encoded, with the application/x-www-form-urlencoded
+// format of https://tools.ietf.org/html/rfc5849#section-3.6.
+func encodeOAuthParams(oauthParams map[string]string) string {
+	keys := make([]string, 0, len(oauthParams))
+	for k := range oauthParams {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+oauthParams[k])
+	}
+	return strings.Join(pairs, "&")
+}
+
+// The appendParams appends encoded parameters to a query string or to a
+// form-encoded body.
+func appendParams(encoded, params string) string {
+	if encoded == "" {
+		return params
+	}
+	return encoded + "&" + params
+}
+
+// The removeOAuthParams removes the oauth parameters written by a previous
+// signature from a raw query or a form-encoded body, so that signing the
+// request again does not sign them: the generated parameters,
+// oauth_signature and the additional parameters the signer sets. Keys are
+// compared once decoded, and other parameters are kept even if their key
+// starts with "oauth_".
+func removeOAuthParams(encoded string, params map[string]string) string {
+	if encoded == "" {
+		return encoded
+	}
+	var kept []string
+	for _, pair := range strings.Split(encoded, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(key); err == nil {
+			if params[decoded] != "" || generatedOAuthParams[decoded] || decoded == oauthSignatureParam {
+				continue
+			}
+		}
+		kept = append(kept, pair)
+	}
+	return strings.Join(kept, "&")
+}
+
+// The setFormBody appends the signed oauth parameters to the form-encoded
+// body of the given http request, which must have been read with
+// readRequestBody, and keeps the body replayable. The body replaced is
+// closed.
+func setFormBody(req *http.Request, body []byte, oauthParams map[string]string) {
+	form := []byte(appendParams(string(body), encodeOAuthParams(oauthParams)))
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	req.Body = io.NopCloser(bytes.NewReader(form))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(form)), nil
+	}
+	req.ContentLength = int64(len(form))
+	req.Header.Set("Content-Type", formContentType)
+}
@@ -0,0 +1,179 @@
+package oauth_test
+
+import (
+	"errors"
+	"fmt"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// The toAuthorizationHeader moves the oauth parameters of the given values
+// into an Authorization header, so that they can be verified.
+func toAuthorizationHeader(values url.Values) string {
+	var params []string
+	for k, v := range values {
+		if strings.HasPrefix(k, "oauth_") {
+			params = append(params, fmt.Sprintf("%v=\"%v\"", k, url.PathEscape(v[0])))
+			values.Del(k)
+		}
+	}
+	sort.Strings(params)
+	return "OAuth " + strings.Join(params, ",")
+}
+
+func TestHttpRequestSigning_ShouldTransmitParamsInQueryString(t *testing.T) {
+
+	// GIVEN
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service?a=1&b=x%20y", strings.NewReader("payload"))
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Transmission: oauth.QueryString}
+
+	// WHEN
+	err := signer.Sign(req)
+	_ = signer.Sign(req) // signing again replaces the parameters
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to sign the http request, got %v", err)
+	}
+	if req.Header.Get(oauth.AuthorizationHeaderName) != "" {
+		t.Errorf("Expected no Authorization header")
+	}
+	query := req.URL.Query()
+	if !strings.HasPrefix(req.URL.RawQuery, "a=1&b=x%20y&oauth_") || len(query["oauth_signature"]) != 1 || query.Get("oauth_consumer_key") != consumerKey {
+		t.Errorf("Expected the oauth parameters in the query, got %v", req.URL.RawQuery)
+	}
+	authHeader := toAuthorizationHeader(query)
+	u, _ := url.Parse("https://sandbox.api.mastercard.com/service?a=1&b=x%20y")
+	if err = oauth.VerifyAuthorizationHeader(authHeader, u, "POST", []byte("payload"), &signingKey.PublicKey); err != nil {
+		t.Errorf("Expected the query parameters to be verified, got %v", err)
+	}
+}
+
+func TestHttpRequestSigning_ShouldOnlyReplaceSignerParamsInQueryString(t *testing.T) {
+
+	// GIVEN
+	req, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service?oauth_callback_url=x&oauth%5Fnonce=stale&oauth_token=stale", nil)
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Token: "token", Transmission: oauth.QueryString}
+
+	// WHEN
+	err := signer.Sign(req)
+	_ = signer.Sign(req)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to sign the http request, got %v", err)
+	}
+	query := req.URL.Query()
+	if !strings.HasPrefix(req.URL.RawQuery, "oauth_callback_url=x&") || query.Get("oauth_callback_url") != "x" {
+		t.Errorf("Expected the parameters of the API to be kept, got %v", req.URL.RawQuery)
+	}
+	if len(query["oauth_nonce"]) != 1 || query.Get("oauth_nonce") == "stale" || len(query["oauth_signature"]) != 1 {
+		t.Errorf("Expected encoded keys of previous signatures to be replaced, got %v", req.URL.RawQuery)
+	}
+	if len(query["oauth_token"]) != 1 || query.Get("oauth_token") != "token" {
+		t.Errorf("Expected the token of the signer to be replaced, got %v", req.URL.RawQuery)
+	}
+}
+
+func TestHttpRequestSigning_ShouldTransmitParamsInFormBody(t *testing.T) {
+
+	// GIVEN
+	withForm, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", strings.NewReader("amount=10.00&note=a+b"))
+	withForm.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	withoutBody, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", nil)
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Transmission: oauth.FormBody}
+
+	for _, req := range []*http.Request{withForm, withoutBody} {
+
+		// WHEN
+		err := signer.Sign(req)
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected to sign the http request, got %v", err)
+		}
+		body, _ := io.ReadAll(req.Body)
+		if int64(len(body)) != req.ContentLength || req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			t.Errorf("Expected a form body, got %v", string(body))
+		}
+		form, _ := url.ParseQuery(string(body))
+		if _, ok := form["oauth_body_hash"]; ok {
+			t.Errorf("Expected no body hash, got %v", string(body))
+		}
+		authHeader := toAuthorizationHeader(form)
+		verified, _ := http.NewRequest("POST", req.URL.String(), strings.NewReader(form.Encode()))
+		verified.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		verified.Header.Set(oauth.AuthorizationHeaderName, authHeader)
+		if err = (&oauth.Verifier{PublicKey: &signingKey.PublicKey}).Verify(verified); err != nil {
+			t.Errorf("Expected the form parameters to be verified, got %v", err)
+		}
+	}
+}
+
+// The closeRecorder records whether a body was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestHttpRequestSigning_ShouldReplaceParamsInFormBody(t *testing.T) {
+
+	// GIVEN
+	body := &closeRecorder{Reader: strings.NewReader("amount=10.00&oauth_callback_url=x")}
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", body)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Token: "token", Transmission: oauth.FormBody}
+
+	// WHEN
+	err := signer.Sign(req)
+	err2 := signer.Sign(req)
+
+	// THEN
+	if err != nil || err2 != nil {
+		t.Fatalf("Expected to sign the http request twice, got %v, %v", err, err2)
+	}
+	if !body.closed {
+		t.Errorf("Expected the replaced body to be closed")
+	}
+	signed, _ := io.ReadAll(req.Body)
+	form, _ := url.ParseQuery(string(signed))
+	if !strings.HasPrefix(string(signed), "amount=10.00&oauth_callback_url=x&") {
+		t.Errorf("Expected the parameters of the API to be kept, got %v", string(signed))
+	}
+	for _, name := range []string{"oauth_consumer_key", "oauth_nonce", "oauth_signature", "oauth_token"} {
+		if len(form[name]) != 1 {
+			t.Errorf("Expected a single %v, got %v", name, string(signed))
+		}
+	}
+	authHeader := toAuthorizationHeader(form)
+	verified, _ := http.NewRequest("POST", req.URL.String(), strings.NewReader(form.Encode()))
+	verified.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	verified.Header.Set(oauth.AuthorizationHeaderName, authHeader)
+	if err = (&oauth.Verifier{PublicKey: &signingKey.PublicKey}).Verify(verified); err != nil {
+		t.Errorf("Expected the form parameters to be verified, got %v", err)
+	}
+}
+
+func TestHttpRequestSigning_ShouldRequireFormBody(t *testing.T) {
+
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	if err := (&oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Transmission: oauth.FormBody}).Sign(req); !errors.Is(err, oauth.ErrFormBodyRequired) {
+		t.Errorf("Expected ErrFormBodyRequired, got %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := (&oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey, Transmission: oauth.FormBody, HashFormBody: true}).Sign(req); !errors.Is(err, oauth.ErrFormBodyRequired) {
+		t.Errorf("Expected ErrFormBodyRequired with HashFormBody, got %v", err)
+	}
+}
@@ -3,12 +3,28 @@ package utils
 
 import (
 	"crypto/rsa"
-	"golang.org/x/crypto/pkcs12"
+	"errors"
 	"io/ioutil"
 	"os"
 )
 
+const (
+	minKeySize = 2048
+)
+
+var (
+	// ErrNotRSAKey is returned when the loaded private key is not an RSA key.
+	ErrNotRSAKey = errors.New("utils: signing key is not an RSA key")
+	// ErrKeyTooSmall is returned when the loaded RSA key is smaller than 2048 bits.
+	ErrKeyTooSmall = errors.New("utils: RSA signing key must be at least 2048 bits")
+	// ErrIncorrectPassword is returned when the key cannot be decrypted with
+	// the given password.
+	ErrIncorrectPassword = errors.New("utils: incorrect password or corrupted key")
+)
+
 // LoadSigningKey loads a RSA signing key out of a PKCS#12 container.
+// Unlike the other loaders, it accepts keys smaller than 2048 bits, for
+// compatibility with existing callers.
 func LoadSigningKey(filePath, password string) (*rsa.PrivateKey, error) {
 
 	// read the file content
@@ -18,12 +34,33 @@ func LoadSigningKey(filePath, password string) (*rsa.PrivateKey, error) {
 	}
 
 	// decode file content to privateKey
-	privateKey, _, err := pkcs12.Decode(privateKeyData, password)
+	entry, err := decodeKeyEntry(privateKeyData, password, "")
 	if err != nil {
 		return nil, err
 	}
 
-	return privateKey.(*rsa.PrivateKey), nil
+	return entry.PrivateKey, nil
+}
+
+// The toSigningKey checks that the private key is an RSA key
+// strong enough to sign requests.
+func toSigningKey(privateKey interface{}) (*rsa.PrivateKey, error) {
+	rsaKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotRSAKey
+	}
+	if err := checkKeySize(rsaKey); err != nil {
+		return nil, err
+	}
+	return rsaKey, nil
+}
+
+// The checkKeySize rejects RSA keys smaller than 2048 bits.
+func checkKeySize(rsaKey *rsa.PrivateKey) error {
+	if rsaKey.N.BitLen() < minKeySize {
+		return ErrKeyTooSmall
+	}
+	return nil
 }
 
 // The readFile fetches the content of a file located on the
@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultPollInterval = 30 * time.Second
+
+// KeyLoader loads the consumer key and the signing key from their source.
+type KeyLoader func() (consumerKey string, signingKey *rsa.PrivateKey, err error)
+
+// FileKeyLoader returns a KeyLoader reading the PKCS#12 or PEM signing key
+// at filePath. The consumer key is resolved with ResolveSecretString on every load,
+// so "file://" references are reloaded along with the signing key.
+func FileKeyLoader(consumerKey, filePath, password, alias string) KeyLoader {
+	return func() (string, *rsa.PrivateKey, error) {
+		value, err := ResolveSecretString(consumerKey)
+		if err != nil {
+			return "", nil, err
+		}
+		data, err := readFile(filePath)
+		if err != nil {
+			return "", nil, err
+		}
+		signingKey, err := LoadSigningKeyFromBytes(data, password, alias)
+		if err != nil {
+			return "", nil, err
+		}
+		return value, signingKey, nil
+	}
+}
+
+// KeyProviderConfig configures a KeyProvider.
+type KeyProviderConfig struct {
+	// Load loads the credentials. It is required.
+	Load KeyLoader
+	// WatchedFiles are polled for changes in modification time or size
+	// and trigger a reload when they change. When empty, Load is invoked
+	// on every poll.
+	WatchedFiles []string
+	// PollInterval defaults to 30 seconds. A negative value disables
+	// polling, in which case credentials are only reloaded by Reload.
+	PollInterval time.Duration
+	// Overlap is how long the previous credentials remain available
+	// through Previous after a rotation.
+	Overlap time.Duration
+	// OnReload, when set, is called after every reload attempt with
+	// the error the reload failed with, if any.
+	OnReload func(err error)
+}
+
+// KeyProviderStats reports the reloads performed by a KeyProvider.
+type KeyProviderStats struct {
+	Reloads    uint64
+	Failures   uint64
+	LastReload time.Time
+	LastError  error
+}
+
+// KeyProvider holds credentials that are reloaded from their source while
+// in use, which allows signing keys to be rotated without restarting. It
+// implements oauth.CredentialsProvider and is safe for concurrent use.
+type KeyProvider struct {
+	config  KeyProviderConfig
+	current atomic.Pointer[credentials]
+
+	mu            sync.Mutex
+	previous      *credentials
+	previousUntil time.Time
+	stamps        map[string]fileStamp
+	stats         KeyProviderStats
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type credentials struct {
+	consumerKey string
+	signingKey  *rsa.PrivateKey
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewKeyProvider loads the credentials and starts watching their source.
+// Close must be called to stop watching.
+func NewKeyProvider(config KeyProviderConfig) (*KeyProvider, error) {
+	if config.Load == nil {
+		return nil, errors.New("utils: key loader must be provided")
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	provider := &KeyProvider{config: config, done: make(chan struct{})}
+	stamps := provider.statFiles()
+	consumerKey, signingKey, err := provider.load()
+	if err != nil {
+		return nil, err
+	}
+	provider.current.Store(&credentials{consumerKey, signingKey})
+	provider.stamps = stamps
+	provider.stats.LastReload = time.Now()
+
+	if config.PollInterval > 0 {
+		go provider.poll()
+	}
+	return provider, nil
+}
+
+// Credentials returns the current consumer key and signing key.
+func (p *KeyProvider) Credentials() (string, crypto.Signer, error) {
+	current := p.current.Load()
+	return current.consumerKey, current.signingKey, nil
+}
+
+// Previous returns the credentials in use before the last rotation, as long
+// as the overlap window has not elapsed. The interceptor Transport signs
+// requests whose signature is rejected with the new key, for instance while
+// it propagates, again with the previous key.
+func (p *KeyProvider) Previous() (string, *rsa.PrivateKey, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.previous == nil || time.Now().After(p.previousUntil) {
+		return "", nil, false
+	}
+	return p.previous.consumerKey, p.previous.signingKey, true
+}
+
+// Reload loads the credentials from their source. The current credentials
+// are kept when loading fails.
+func (p *KeyProvider) Reload() error {
+	p.mu.Lock()
+	err := p.reload(p.statFiles())
+	onReload := p.config.OnReload
+	p.mu.Unlock()
+
+	if onReload != nil {
+		onReload(err)
+	}
+	return err
+}
+
+// Stats returns the reload statistics.
+func (p *KeyProvider) Stats() KeyProviderStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// Close stops watching the credentials source.
+func (p *KeyProvider) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+}
+
+// The reload loads the credentials and swaps them with the current ones.
+// It must be called with the lock held.
+func (p *KeyProvider) reload(stamps map[string]fileStamp) error {
+	consumerKey, signingKey, err := p.load()
+	if err != nil {
+		p.stats.Failures++
+		p.stats.LastError = err
+		return err
+	}
+
+	old := p.current.Load()
+	if old.consumerKey != consumerKey || !old.signingKey.Equal(signingKey) {
+		p.current.Store(&credentials{consumerKey, signingKey})
+		p.previous = old
+		p.previousUntil = time.Now().Add(p.config.Overlap)
+	}
+	p.stamps = stamps
+	p.stats.Reloads++
+	p.stats.LastReload = time.Now()
+	p.stats.LastError = nil
+	return nil
+}
+
+// The load invokes the key loader and rejects empty credentials.
+func (p *KeyProvider) load() (string, *rsa.PrivateKey, error) {
+	consumerKey, signingKey, err := p.config.Load()
+	if err == nil && (consumerKey == "" || signingKey == nil) {
+		err = errors.New("utils: key loader returned empty credentials")
+	}
+	return consumerKey, signingKey, err
+}
+
+// The poll reloads the credentials every poll interval when the watched
+// files changed, or unconditionally when no file is watched.
+func (p *KeyProvider) poll() {
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			if p.changed() {
+				_ = p.Reload()
+			}
+		}
+	}
+}
+
+// The changed reports whether any watched file changed since the
+// last successful reload.
+func (p *KeyProvider) changed() bool {
+	if len(p.config.WatchedFiles) == 0 {
+		return true
+	}
+	stamps := p.statFiles()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, stamp := range stamps {
+		if recorded, ok := p.stamps[name]; !ok || !recorded.modTime.Equal(stamp.modTime) || recorded.size != stamp.size {
+			return true
+		}
+	}
+	return false
+}
+
+// The statFiles records the modification time and size of the watched
+// files. Missing files are recorded with a zero stamp.
+func (p *KeyProvider) statFiles() map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(p.config.WatchedFiles))
+	for _, name := range p.config.WatchedFiles {
+		if info, err := os.Stat(name); err == nil {
+			stamps[name] = fileStamp{info.ModTime(), info.Size()}
+		} else {
+			stamps[name] = fileStamp{}
+		}
+	}
+	return stamps
+}
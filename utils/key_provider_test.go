@@ -0,0 +1,172 @@
+package utils_test
+
+import (
+	"crypto/rsa"
+	"errors"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func copyKeyFile(t *testing.T, src, dst string, modTime time.Time) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(dst, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(dst, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func currentKey(provider *utils.KeyProvider) *rsa.PrivateKey {
+	_, key, _ := provider.Credentials()
+	return key.(*rsa.PrivateKey)
+}
+
+func TestKeyProvider_ShouldReloadRotatedKey(t *testing.T) {
+
+	// GIVEN
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "signing_key.pem")
+	copyKeyFile(t, "../testdata/test_key_pkcs8.pem", keyFile, time.Now().Add(-time.Hour))
+	original, _ := utils.LoadSigningKeyFromPEMFile("../testdata/test_key_pkcs8.pem", "")
+	rotated, _ := utils.LoadSigningKeyFromPEMFile("../testdata/test_key_rotated.pem", "")
+
+	reloaded := make(chan error, 1)
+	provider, err := utils.NewKeyProvider(utils.KeyProviderConfig{
+		Load:         utils.FileKeyLoader("consumer_key", keyFile, "", ""),
+		WatchedFiles: []string{keyFile},
+		PollInterval: 10 * time.Millisecond,
+		Overlap:      time.Minute,
+		OnReload:     func(err error) { reloaded <- err },
+	})
+	if err != nil {
+		t.Fatalf("Expected the provider to load the key, got %v", err)
+	}
+	defer provider.Close()
+	if !currentKey(provider).Equal(original) {
+		t.Fatalf("Expected the original key")
+	}
+	if _, _, ok := provider.Previous(); ok {
+		t.Errorf("Expected no previous key before rotation")
+	}
+
+	// WHEN
+	copyKeyFile(t, "../testdata/test_key_rotated.pem", keyFile, time.Now())
+
+	// THEN
+	select {
+	case err = <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the rotated key to be reloaded")
+	}
+	if err != nil {
+		t.Fatalf("Expected the reload to succeed, got %v", err)
+	}
+	if !currentKey(provider).Equal(rotated) {
+		t.Errorf("Expected the rotated key")
+	}
+	if _, previous, ok := provider.Previous(); !ok || !previous.Equal(original) {
+		t.Errorf("Expected the original key to remain available during the overlap")
+	}
+	if stats := provider.Stats(); stats.Reloads != 1 || stats.Failures != 0 {
+		t.Errorf("Expected 1 reload and no failure, got %+v", stats)
+	}
+}
+
+func TestKeyProvider_ShouldKeepKeyOnReloadFailure(t *testing.T) {
+
+	// GIVEN
+	fail := errors.New("unavailable")
+	var loadErr error
+	signingKey, _ := utils.LoadSigningKeyFromPEMFile("../testdata/test_key_pkcs8.pem", "")
+	var failures []error
+	provider, _ := utils.NewKeyProvider(utils.KeyProviderConfig{
+		Load: func() (string, *rsa.PrivateKey, error) {
+			return "consumer_key", signingKey, loadErr
+		},
+		PollInterval: -1,
+		OnReload:     func(err error) { failures = append(failures, err) },
+	})
+	defer provider.Close()
+
+	// WHEN
+	loadErr = fail
+	err := provider.Reload()
+
+	// THEN
+	if !errors.Is(err, fail) || len(failures) != 1 || !errors.Is(failures[0], fail) {
+		t.Errorf("Expected the failure to be reported, got %v", err)
+	}
+	if consumerKey, _, _ := provider.Credentials(); consumerKey != "consumer_key" || !currentKey(provider).Equal(signingKey) {
+		t.Errorf("Expected the current credentials to be kept")
+	}
+	if stats := provider.Stats(); stats.Failures != 1 || !errors.Is(stats.LastError, fail) {
+		t.Errorf("Expected the failure in stats, got %+v", stats)
+	}
+}
+
+func TestKeyProvider_ShouldSignConcurrentlyDuringRotation(t *testing.T) {
+
+	// GIVEN
+	keys := make([]*rsa.PrivateKey, 2)
+	keys[0], _ = utils.LoadSigningKeyFromPEMFile("../testdata/test_key_pkcs8.pem", "")
+	keys[1], _ = utils.LoadSigningKeyFromPEMFile("../testdata/test_key_rotated.pem", "")
+	var mu sync.Mutex
+	loads := 0
+	provider, _ := utils.NewKeyProvider(utils.KeyProviderConfig{
+		Load: func() (string, *rsa.PrivateKey, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			loads++
+			return "consumer_key", keys[loads%2], nil
+		},
+		PollInterval: time.Millisecond,
+	})
+	defer provider.Close()
+	signer := &oauth.Signer{Credentials: provider}
+
+	// WHEN
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service", nil)
+			errs <- signer.Sign(req)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// THEN
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected to sign the request, got %v", err)
+		}
+	}
+}
+
+func TestNewKeyProviderInvalidInput(t *testing.T) {
+
+	if _, err := utils.NewKeyProvider(utils.KeyProviderConfig{}); err == nil {
+		t.Errorf("Expected to throw error in case of missing loader")
+	}
+	loader := utils.FileKeyLoader("consumer_key", "../testdata/invalidFile.pem", "", "")
+	if _, err := utils.NewKeyProvider(utils.KeyProviderConfig{Load: loader}); err == nil {
+		t.Errorf("Expected to throw error in case of missing key file")
+	}
+	loader = utils.FileKeyLoader("", "../testdata/test_key_pkcs8.pem", "", "")
+	if _, err := utils.NewKeyProvider(utils.KeyProviderConfig{Load: loader}); err == nil {
+		t.Errorf("Expected to throw error in case of empty consumer key")
+	}
+}
@@ -0,0 +1,321 @@
+package utils
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultCacheTTL = 5 * time.Minute
+
+// ErrCertificateExpired is returned when the certificate of a consumer is
+// expired or not yet valid. It reports the consumer key as rejected, see
+// KeyRejected.
+var ErrCertificateExpired error = keyRejectedError("utils: certificate expired or not yet valid")
+
+// The keyRejectedError reports a known consumer key whose public key must
+// not be used.
+type keyRejectedError string
+
+func (e keyRejectedError) Error() string {
+	return string(e)
+}
+
+// KeyRejected tells callers such as the middleware package that the
+// consumer key is known but rejected, without importing this package.
+func (keyRejectedError) KeyRejected() bool {
+	return true
+}
+
+// publicKeyExtensions lists the files read by DirectoryKeySource.
+var publicKeyExtensions = map[string]bool{".pem": true, ".crt": true, ".cer": true, ".der": true}
+
+// PublicKeyEntry is the public key of a consumer, along with the certificate
+// it was read from, if any.
+type PublicKeyEntry struct {
+	PublicKey   *rsa.PublicKey
+	Certificate *x509.Certificate
+}
+
+// PublicKeySource loads the public keys of the consumers, keyed by consumer
+// key.
+type PublicKeySource func() (map[string]*PublicKeyEntry, error)
+
+// DirectoryKeySource returns a PublicKeySource reading the X.509 certificates
+// and PEM public keys of a directory. The consumer key is the name of the
+// file without its extension (.pem, .crt, .cer or .der).
+func DirectoryKeySource(dir string) PublicKeySource {
+	return func() (map[string]*PublicKeyEntry, error) {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		entries := make(map[string]*PublicKeyEntry)
+		for _, file := range files {
+			ext := strings.ToLower(filepath.Ext(file.Name()))
+			if file.IsDir() || strings.HasPrefix(file.Name(), ".") || !publicKeyExtensions[ext] {
+				continue
+			}
+			entry, err := LoadPublicKeyEntry(filepath.Join(dir, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			entries[strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))] = entry
+		}
+		return entries, nil
+	}
+}
+
+// manifest lists the public keys of the consumers:
+//
+//	consumers:
+//	  - consumerKey: "<consumer key>"
+//	    publicKey: certs/consumer.pem # or inline PEM data
+type manifest struct {
+	Consumers []struct {
+		ConsumerKey string `json:"consumerKey" yaml:"consumerKey"`
+		PublicKey   string `json:"publicKey" yaml:"publicKey"`
+	} `json:"consumers" yaml:"consumers"`
+}
+
+// ManifestKeySource returns a PublicKeySource reading a JSON or YAML
+// manifest, depending on the file extension. Every consumer has a public
+// key given either as inline PEM data or as the path of a certificate or
+// PEM public key file, relative to the manifest.
+func ManifestKeySource(path string) PublicKeySource {
+	return func() (map[string]*PublicKeyEntry, error) {
+		data, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var m manifest
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &m)
+		default:
+			err = json.Unmarshal(data, &m)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("utils: invalid manifest %v: %w", path, err)
+		}
+		entries := make(map[string]*PublicKeyEntry, len(m.Consumers))
+		for _, consumer := range m.Consumers {
+			if consumer.ConsumerKey == "" {
+				return nil, fmt.Errorf("utils: invalid manifest %v: missing consumer key", path)
+			}
+			var entry *PublicKeyEntry
+			if strings.HasPrefix(strings.TrimSpace(consumer.PublicKey), "-----BEGIN") {
+				entry, err = parsePublicKeyEntry([]byte(consumer.PublicKey))
+			} else {
+				file := consumer.PublicKey
+				if !filepath.IsAbs(file) {
+					file = filepath.Join(filepath.Dir(path), file)
+				}
+				entry, err = LoadPublicKeyEntry(file)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("utils: invalid public key of %v: %w", consumer.ConsumerKey, err)
+			}
+			entries[consumer.ConsumerKey] = entry
+		}
+		return entries, nil
+	}
+}
+
+// PKCS12KeySource returns a PublicKeySource reading the public key of the
+// given consumer out of the certificate chain of a PKCS#12 container, as
+// read by LoadSigningKeyEntry. Containers without certificate provide the
+// public key of the private key.
+func PKCS12KeySource(consumerKey, filePath, password, alias string) PublicKeySource {
+	return func() (map[string]*PublicKeyEntry, error) {
+		keyEntry, err := LoadSigningKeyEntry(filePath, password, alias)
+		if err != nil {
+			return nil, err
+		}
+		entry := &PublicKeyEntry{PublicKey: &keyEntry.PrivateKey.PublicKey}
+		if len(keyEntry.CertificateChain) > 0 {
+			entry.Certificate = keyEntry.CertificateChain[0]
+		}
+		return map[string]*PublicKeyEntry{consumerKey: entry}, nil
+	}
+}
+
+// PublicKeyResolverConfig configures a PublicKeyResolver.
+type PublicKeyResolverConfig struct {
+	// Sources are loaded in order, and the keys of later sources replace
+	// the keys of earlier ones for the same consumer key.
+	Sources []PublicKeySource
+	// CacheTTL is how long the keys are cached before the sources are
+	// loaded again, 5 minutes by default. A negative value disables the
+	// reloads, in which case keys are only reloaded by Reload.
+	CacheTTL time.Duration
+	// OnReload, when set, is called after every reload attempt with the
+	// error the reload failed with, if any.
+	OnReload func(err error)
+}
+
+// PublicKeyResolver resolves the public key of a consumer key out of
+// certificate files, PEM public keys or PKCS#12 containers. It implements
+// the oauth.KeyResolver interface. Keys are cached and failed reloads keep
+// the cached keys. Certificates are checked for expiry on every request.
+type PublicKeyResolver struct {
+	config PublicKeyResolverConfig
+
+	mu       sync.RWMutex
+	entries  map[string]*PublicKeyEntry
+	loadedAt time.Time
+
+	reloadMu  sync.Mutex
+	reloading atomic.Bool
+}
+
+// NewPublicKeyResolver loads the keys of the sources.
+func NewPublicKeyResolver(config PublicKeyResolverConfig) (*PublicKeyResolver, error) {
+	if len(config.Sources) == 0 {
+		return nil, errors.New("utils: provide at least one public key source")
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = defaultCacheTTL
+	}
+	r := &PublicKeyResolver{config: config}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ResolveKey returns the public key of the consumer key, or nil when the
+// consumer key is unknown. ErrCertificateExpired is returned when the
+// certificate of the consumer is not valid at the current time. Once the
+// keys are stale, the first caller reloads them while the other callers
+// are served the stale keys.
+func (r *PublicKeyResolver) ResolveKey(_ context.Context, consumerKey string) (*rsa.PublicKey, error) {
+	now := time.Now()
+	if r.stale(now) && r.reloading.CompareAndSwap(false, true) {
+		// the keys may have been reloaded since they were found stale
+		if r.stale(now) {
+			_ = r.Reload()
+		}
+		r.reloading.Store(false)
+	}
+
+	r.mu.RLock()
+	entry := r.entries[consumerKey]
+	r.mu.RUnlock()
+	if entry == nil {
+		return nil, nil
+	}
+	if cert := entry.Certificate; cert != nil && (now.Before(cert.NotBefore) || now.After(cert.NotAfter)) {
+		return nil, fmt.Errorf("%w: %v", ErrCertificateExpired, consumerKey)
+	}
+	return entry.PublicKey, nil
+}
+
+// The stale reports whether the keys must be reloaded at the given time.
+func (r *PublicKeyResolver) stale(now time.Time) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config.CacheTTL > 0 && now.Sub(r.loadedAt) >= r.config.CacheTTL
+}
+
+// Reload loads the keys of the sources. The cached keys are kept when
+// any source fails, until the next reload. Reloads are serialized.
+func (r *PublicKeyResolver) Reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	entries := make(map[string]*PublicKeyEntry)
+	var err error
+	for _, source := range r.config.Sources {
+		var loaded map[string]*PublicKeyEntry
+		if loaded, err = source(); err != nil {
+			break
+		}
+		for consumerKey, entry := range loaded {
+			entries[consumerKey] = entry
+		}
+	}
+
+	r.mu.Lock()
+	r.loadedAt = time.Now()
+	if err == nil {
+		r.entries = entries
+	}
+	r.mu.Unlock()
+	if r.config.OnReload != nil {
+		r.config.OnReload(err)
+	}
+	return err
+}
+
+// LoadPublicKeyEntry loads the RSA public key of a PEM or DER encoded X.509
+// certificate, or of a PEM public key file.
+func LoadPublicKeyEntry(path string) (*PublicKeyEntry, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := parsePublicKeyEntry(data)
+	if err != nil {
+		return nil, fmt.Errorf("utils: %v: %w", path, err)
+	}
+	return entry, nil
+}
+
+// The parsePublicKeyEntry parses the first certificate or RSA public key of
+// PEM data, or a DER encoded certificate.
+func parsePublicKeyEntry(data []byte) (*PublicKeyEntry, error) {
+	for rest := data; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			return parseCertificate(block.Bytes)
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			return toPublicKeyEntry(key, nil)
+		case "RSA PUBLIC KEY":
+			key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			return toPublicKeyEntry(key, nil)
+		}
+	}
+	if len(data) > 0 && data[0] == 0x30 {
+		return parseCertificate(data)
+	}
+	return nil, errors.New("no certificate or public key found")
+}
+
+func parseCertificate(der []byte) (*PublicKeyEntry, error) {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return toPublicKeyEntry(cert.PublicKey, cert)
+}
+
+// The toPublicKeyEntry checks that the public key is an RSA key.
+func toPublicKeyEntry(key interface{}, cert *x509.Certificate) (*PublicKeyEntry, error) {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return &PublicKeyEntry{PublicKey: rsaKey, Certificate: cert}, nil
+}
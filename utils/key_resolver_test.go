@@ -0,0 +1,215 @@
+package utils_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// The writeCertificate writes a self-signed PEM certificate for the key.
+func writeCertificate(t *testing.T, path string, key *rsa.PrivateKey, notAfter time.Time) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test Consumer"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func publicKeyPEM(key *rsa.PrivateKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestPublicKeyResolver_ShouldResolveKeysOfDirectory(t *testing.T) {
+
+	// GIVEN
+	dir := t.TempDir()
+	original, _ := utils.LoadSigningKeyFromPEMFile("../testdata/test_key_pkcs8.pem", "")
+	rotated, _ := utils.LoadSigningKeyFromPEMFile("../testdata/test_key_rotated.pem", "")
+	writeCertificate(t, filepath.Join(dir, "consumer_a.crt"), original, time.Now().Add(time.Hour))
+	writeCertificate(t, filepath.Join(dir, "consumer_b.pem"), rotated, time.Now().Add(-time.Hour))
+	_ = os.WriteFile(filepath.Join(dir, "consumer_c.pem"), []byte(publicKeyPEM(rotated)), 0600)
+	_ = os.WriteFile(filepath.Join(dir, "README.txt"), []byte("ignored"), 0600)
+
+	// WHEN
+	resolver, err := utils.NewPublicKeyResolver(utils.PublicKeyResolverConfig{Sources: []utils.PublicKeySource{utils.DirectoryKeySource(dir)}})
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the keys to be loaded, got %v", err)
+	}
+	var _ oauth.KeyResolver = resolver
+	if key, err := resolver.ResolveKey(context.Background(), "consumer_a"); err != nil || !key.Equal(&original.PublicKey) {
+		t.Errorf("Expected the key of the certificate, got %v", err)
+	}
+	if _, err := resolver.ResolveKey(context.Background(), "consumer_b"); !errors.Is(err, utils.ErrCertificateExpired) {
+		t.Errorf("Expected ErrCertificateExpired, got %v", err)
+	}
+	if key, err := resolver.ResolveKey(context.Background(), "consumer_c"); err != nil || !key.Equal(&rotated.PublicKey) {
+		t.Errorf("Expected the PEM public key, got %v", err)
+	}
+	if key, err := resolver.ResolveKey(context.Background(), "README"); key != nil || err != nil {
+		t.Errorf("Expected no key for an unknown consumer key, got %v, %v", key, err)
+	}
+}
+
+func TestPublicKeyResolver_ShouldResolveKeysOfManifest(t *testing.T) {
+
+	// GIVEN
+	dir := t.TempDir()
+	original, _ := utils.LoadSigningKeyFromPEMFile("../testdata/test_key_pkcs8.pem", "")
+	rotated, _ := utils.LoadSigningKeyFromPEMFile("../testdata/test_key_rotated.pem", "")
+	writeCertificate(t, filepath.Join(dir, "consumer.crt"), original, time.Now().Add(time.Hour))
+	inline := "      " + strings.ReplaceAll(strings.TrimSpace(publicKeyPEM(rotated)), "\n", "\n      ")
+	manifests := map[string]string{
+		"keys.yaml": "consumers:\n  - consumerKey: consumer_a\n    publicKey: consumer.crt\n  - consumerKey: consumer_b\n    publicKey: |\n" + inline + "\n",
+		"keys.json": `{"consumers": [{"consumerKey": "consumer_a", "publicKey": "consumer.crt"}, {"consumerKey": "consumer_b", "publicKey": ` +
+			strings.ReplaceAll(`"`+publicKeyPEM(rotated)+`"`, "\n", `\n`) + `}]}`,
+	}
+	for name, manifest := range manifests {
+		path := filepath.Join(dir, name)
+		_ = os.WriteFile(path, []byte(manifest), 0600)
+
+		// WHEN
+		resolver, err := utils.NewPublicKeyResolver(utils.PublicKeyResolverConfig{Sources: []utils.PublicKeySource{utils.ManifestKeySource(path)}})
+
+		// THEN
+		if err != nil {
+			t.Fatalf("Expected the keys of %v to be loaded, got %v", name, err)
+		}
+		if key, err := resolver.ResolveKey(context.Background(), "consumer_a"); err != nil || !key.Equal(&original.PublicKey) {
+			t.Errorf("Expected the key of the certificate file in %v, got %v", name, err)
+		}
+		if key, err := resolver.ResolveKey(context.Background(), "consumer_b"); err != nil || !key.Equal(&rotated.PublicKey) {
+			t.Errorf("Expected the inline key in %v, got %v", name, err)
+		}
+	}
+
+	_ = os.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{"consumers": [{"publicKey": "consumer.crt"}]}`), 0600)
+	if _, err := utils.NewPublicKeyResolver(utils.PublicKeyResolverConfig{Sources: []utils.PublicKeySource{utils.ManifestKeySource(filepath.Join(dir, "invalid.json"))}}); err == nil {
+		t.Errorf("Expected an error in case of missing consumer key")
+	}
+}
+
+func TestPublicKeyResolver_ShouldResolveKeysOfPKCS12Containers(t *testing.T) {
+
+	// GIVEN
+	valid, _ := utils.LoadSigningKeyEntry("../testdata/test_key_container_aes.p12", "Password1", "")
+
+	// WHEN
+	resolver, err := utils.NewPublicKeyResolver(utils.PublicKeyResolverConfig{Sources: []utils.PublicKeySource{
+		utils.PKCS12KeySource("expired", "../testdata/test_key_container.p12", "Password1", ""),
+		utils.PKCS12KeySource("valid", "../testdata/test_key_container_aes.p12", "Password1", ""),
+	}})
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected the keys to be loaded, got %v", err)
+	}
+	if _, err := resolver.ResolveKey(context.Background(), "expired"); !errors.Is(err, utils.ErrCertificateExpired) {
+		t.Errorf("Expected ErrCertificateExpired, got %v", err)
+	}
+	key, err := resolver.ResolveKey(context.Background(), "valid")
+	if valid.CertificateChain[0].NotBefore.After(time.Now()) {
+		// the certificate of the container is not valid yet
+		if !errors.Is(err, utils.ErrCertificateExpired) {
+			t.Errorf("Expected ErrCertificateExpired, got %v", err)
+		}
+	} else if err != nil || !key.Equal(&valid.PrivateKey.PublicKey) {
+		t.Errorf("Expected the key of the certificate, got %v", err)
+	}
+}
+
+func TestPublicKeyResolver_ShouldKeepKeysWhenReloadFails(t *testing.T) {
+
+	// GIVEN
+	dir := t.TempDir()
+	original, _ := utils.LoadSigningKeyFromPEMFile("../testdata/test_key_pkcs8.pem", "")
+	_ = os.WriteFile(filepath.Join(dir, "consumer.pem"), []byte(publicKeyPEM(original)), 0600)
+	var reloads []error
+	resolver, _ := utils.NewPublicKeyResolver(utils.PublicKeyResolverConfig{
+		Sources:  []utils.PublicKeySource{utils.DirectoryKeySource(dir)},
+		CacheTTL: time.Nanosecond,
+		OnReload: func(err error) { reloads = append(reloads, err) },
+	})
+
+	// WHEN
+	_ = os.WriteFile(filepath.Join(dir, "consumer.pem"), []byte("invalid"), 0600)
+	key, err := resolver.ResolveKey(context.Background(), "consumer")
+
+	// THEN
+	if err != nil || !key.Equal(&original.PublicKey) {
+		t.Errorf("Expected the cached key, got %v", err)
+	}
+	if len(reloads) != 2 || reloads[0] != nil || reloads[1] == nil {
+		t.Errorf("Expected the failed reload to be reported, got %v", reloads)
+	}
+	if err = resolver.Reload(); err == nil {
+		t.Errorf("Expected an error in case of invalid key file")
+	}
+}
+
+func TestPublicKeyResolver_ShouldServeStaleKeysDuringReload(t *testing.T) {
+
+	// GIVEN
+	original, _ := utils.LoadSigningKeyFromPEMFile("../testdata/test_key_pkcs8.pem", "")
+	var loads int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	source := func() (map[string]*utils.PublicKeyEntry, error) {
+		if atomic.AddInt32(&loads, 1) == 2 {
+			close(entered)
+			<-release
+		}
+		return map[string]*utils.PublicKeyEntry{"consumer": {PublicKey: &original.PublicKey}}, nil
+	}
+	resolver, _ := utils.NewPublicKeyResolver(utils.PublicKeyResolverConfig{
+		Sources:  []utils.PublicKeySource{source},
+		CacheTTL: time.Nanosecond,
+	})
+	done := make(chan struct{})
+	go func() {
+		_, _ = resolver.ResolveKey(context.Background(), "consumer")
+		close(done)
+	}()
+	<-entered
+
+	// WHEN
+	var keys []*rsa.PublicKey
+	for i := 0; i < 10; i++ {
+		key, _ := resolver.ResolveKey(context.Background(), "consumer")
+		keys = append(keys, key)
+	}
+	close(release)
+	<-done
+
+	// THEN
+	if n := atomic.LoadInt32(&loads); n != 2 {
+		t.Errorf("Expected a single reload at a time, got %v loads", n)
+	}
+	for _, key := range keys {
+		if !key.Equal(&original.PublicKey) {
+			t.Fatalf("Expected the stale key to be served during the reload")
+		}
+	}
+}
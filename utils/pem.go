@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/youmark/pkcs8"
+	"io"
+)
+
+const (
+	pemTypeRSAPrivateKey       = "RSA PRIVATE KEY"
+	pemTypePrivateKey          = "PRIVATE KEY"
+	pemTypeEncryptedPrivateKey = "ENCRYPTED PRIVATE KEY"
+	pemTypeECPrivateKey        = "EC PRIVATE KEY"
+)
+
+// LoadSigningKeyFromPEMFile loads a RSA signing key out of a PEM file. PKCS#1
+// (RSA PRIVATE KEY), PKCS#8 (PRIVATE KEY) and PBES2 encrypted PKCS#8
+// (ENCRYPTED PRIVATE KEY) keys are supported. The password is only
+// used for encrypted keys.
+func LoadSigningKeyFromPEMFile(filePath, password string) (*rsa.PrivateKey, error) {
+	data, err := readFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return LoadSigningKeyFromPEM(data, password)
+}
+
+// LoadSigningKeyFromPEMReader loads a RSA signing key out of PEM data read
+// from r. See LoadSigningKeyFromPEMFile for the supported formats.
+func LoadSigningKeyFromPEMReader(r io.Reader, password string) (*rsa.PrivateKey, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return LoadSigningKeyFromPEM(data, password)
+}
+
+// LoadSigningKeyFromPEM loads a RSA signing key out of PEM data. The first
+// private key block is used, other blocks such as certificates are skipped.
+// See LoadSigningKeyFromPEMFile for the supported formats.
+func LoadSigningKeyFromPEM(data []byte, password string) (*rsa.PrivateKey, error) {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("utils: no private key found in PEM data")
+		}
+		switch block.Type {
+		case pemTypeRSAPrivateKey:
+			if _, ok := block.Headers["Proc-Type"]; ok {
+				return nil, errors.New("utils: legacy PEM encryption is not supported, use encrypted PKCS#8")
+			}
+			privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			return toSigningKey(privateKey)
+		case pemTypePrivateKey:
+			privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			return toSigningKey(privateKey)
+		case pemTypeEncryptedPrivateKey:
+			privateKey, err := decryptPKCS8PrivateKey(block.Bytes, password)
+			if err != nil {
+				return nil, err
+			}
+			return toSigningKey(privateKey)
+		case pemTypeECPrivateKey:
+			return nil, ErrNotRSAKey
+		}
+		data = rest
+	}
+}
+
+// The decryptPKCS8PrivateKey decrypts and parses an EncryptedPrivateKeyInfo.
+func decryptPKCS8PrivateKey(der []byte, password string) (interface{}, error) {
+	privateKey, err := pkcs8.ParsePKCS8PrivateKey(der, []byte(password))
+	if err != nil {
+		// a wrong password leaves data that cannot be parsed
+		return nil, fmt.Errorf("%w: %v", ErrIncorrectPassword, err)
+	}
+	return privateKey, nil
+}
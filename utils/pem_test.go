@@ -0,0 +1,69 @@
+package utils_test
+
+import (
+	"errors"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadSigningKeyFromPEMFile(t *testing.T) {
+
+	expected, _ := utils.LoadSigningKey("../testdata/test_key_container.p12", "Password1")
+	for _, path := range []string{
+		"../testdata/test_key_pkcs1.pem",
+		"../testdata/test_key_pkcs8.pem",
+		"../testdata/test_key_pkcs8_encrypted.pem",
+		"../testdata/test_key_pkcs8_encrypted_des3.pem",
+	} {
+		privateKey, err := utils.LoadSigningKeyFromPEMFile(path, "Password1")
+
+		if err != nil || privateKey == nil {
+			t.Errorf("Expected to load RSA privateKey from %v, but thrown %v", path, err)
+			continue
+		}
+		if !privateKey.Equal(expected) {
+			t.Errorf("Expected the key from %v to match the PKCS#12 key", path)
+		}
+	}
+}
+
+func TestLoadSigningKeyFromPEMReader_ShouldSkipNonKeyBlocks(t *testing.T) {
+
+	keyData, _ := os.ReadFile("../testdata/test_key_pkcs8.pem")
+	data := "-----BEGIN CERTIFICATE-----\nMAA=\n-----END CERTIFICATE-----\n" + string(keyData)
+
+	privateKey, err := utils.LoadSigningKeyFromPEMReader(strings.NewReader(data), "")
+
+	if err != nil || privateKey == nil {
+		t.Errorf("Expected to load RSA privateKey, but thrown %v", err)
+	}
+}
+
+func TestLoadSigningKeyFromPEMInvalidInput(t *testing.T) {
+
+	tests := []struct {
+		path     string
+		password string
+		expected error
+	}{
+		{"../testdata/test_key_pkcs8_encrypted.pem", "incorrect_password", utils.ErrIncorrectPassword},
+		{"../testdata/test_key_pkcs8_encrypted_des3.pem", "incorrect_password", utils.ErrIncorrectPassword},
+		{"../testdata/test_ec_key.pem", "", utils.ErrNotRSAKey},
+		{"../testdata/test_key_1024.pem", "", utils.ErrKeyTooSmall},
+	}
+	for _, test := range tests {
+		privateKey, err := utils.LoadSigningKeyFromPEMFile(test.path, test.password)
+		if !errors.Is(err, test.expected) || privateKey != nil {
+			t.Errorf("Expected %v for %v, got %v", test.expected, test.path, err)
+		}
+	}
+
+	if _, err := utils.LoadSigningKeyFromPEMFile("../testdata/invalidFile.pem", ""); err == nil {
+		t.Errorf("Expected to throw error in case of missing file")
+	}
+	if _, err := utils.LoadSigningKeyFromPEM([]byte("not a PEM"), ""); err == nil {
+		t.Errorf("Expected to throw error in case of invalid PEM data")
+	}
+}
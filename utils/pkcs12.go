@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"software.sslmate.com/src/go-pkcs12"
+	"strings"
+)
+
+// ErrAliasNotFound is returned when no key with the given alias
+// (friendly name) exists in the PKCS#12 container.
+var ErrAliasNotFound = errors.New("utils: no key found for alias")
+
+// KeyEntry is a signing key loaded out of a PKCS#12 container along with
+// its certificate chain.
+type KeyEntry struct {
+	// Alias is the friendly name of the key, if any.
+	Alias      string
+	PrivateKey *rsa.PrivateKey
+	// CertificateChain starts with the certificate of the key, followed by
+	// the issuer certificates found in the container. It is empty when the
+	// container holds no certificate for the key.
+	CertificateChain []*x509.Certificate
+}
+
+// The pkcs12Bag is a key or a certificate of a PKCS#12 container along
+// with its attributes.
+type pkcs12Bag struct {
+	alias       string
+	localKeyID  string
+	privateKey  interface{}
+	certificate *x509.Certificate
+}
+
+// LoadSigningKeyEntry loads the RSA signing key with the given alias (friendly
+// name) out of a PKCS#12 container, along with its certificate chain. An empty
+// alias selects the only key of the container. Both legacy (3DES, RC2, SHA-1
+// MAC) and modern (PBES2 with AES, SHA-2 MAC) containers are supported. Keys
+// smaller than 2048 bits are rejected with ErrKeyTooSmall.
+//
+// Containers holding several keys must store the keys in one safe and the
+// certificates in another, as keytool and OpenSSL do.
+func LoadSigningKeyEntry(filePath, password, alias string) (*KeyEntry, error) {
+	data, err := readFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return LoadSigningKeyEntryFromBytes(data, password, alias)
+}
+
+// LoadSigningKeyEntryFromBytes is like LoadSigningKeyEntry but decodes
+// the PKCS#12 container out of data.
+func LoadSigningKeyEntryFromBytes(data []byte, password, alias string) (*KeyEntry, error) {
+	entry, err := decodeKeyEntry(data, password, alias)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkKeySize(entry.PrivateKey); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// The decodeKeyEntry decodes the key with the given alias and its
+// certificate chain out of a PKCS#12 container, whatever the size of the key.
+func decodeKeyEntry(data []byte, password, alias string) (*KeyEntry, error) {
+	bags, err := decodeBags(data, password)
+	var notImplemented pkcs12.NotImplementedError
+	if errors.As(err, &notImplemented) && alias == "" {
+		// containers of a single safe hold a single key
+		return decodeChain(data, password)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys, certs []*pkcs12Bag
+	for _, bag := range bags {
+		if bag.privateKey != nil {
+			keys = append(keys, bag)
+		} else {
+			certs = append(certs, bag)
+		}
+	}
+
+	var key *pkcs12Bag
+	switch {
+	case alias != "":
+		for _, k := range keys {
+			// aliases are case insensitive, as in Java key stores
+			if strings.EqualFold(k.alias, alias) {
+				key = k
+				break
+			}
+		}
+		if key == nil {
+			return nil, fmt.Errorf("%w: %v", ErrAliasNotFound, alias)
+		}
+	case len(keys) == 1:
+		key = keys[0]
+	case len(keys) == 0:
+		return nil, errors.New("utils: no private key found in PKCS#12 data")
+	default:
+		return nil, errors.New("utils: PKCS#12 data holds several private keys, provide an alias")
+	}
+
+	rsaKey, ok := key.privateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotRSAKey
+	}
+	return &KeyEntry{
+		Alias:            key.alias,
+		PrivateKey:       rsaKey,
+		CertificateChain: buildCertificateChain(key, rsaKey, certs),
+	}, nil
+}
+
+// The decodeBags returns the keys and the certificates of a PKCS#12
+// container along with their friendly name and local key ID. ToPEM is the
+// only go-pkcs12 function returning every safe bag with its attributes. It
+// is deprecated for the PEM blocks it produces, whose RSA keys are PKCS#1
+// keys labelled PRIVATE KEY, which is how they are parsed here.
+func decodeBags(data []byte, password string) ([]*pkcs12Bag, error) {
+	blocks, err := pkcs12.ToPEM(data, password)
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) || errors.Is(err, pkcs12.ErrDecryption) {
+		return nil, ErrIncorrectPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+	bags := make([]*pkcs12Bag, 0, len(blocks))
+	for _, block := range blocks {
+		bag := &pkcs12Bag{alias: block.Headers["friendlyName"], localKeyID: block.Headers["localKeyId"]}
+		switch block.Type {
+		case "CERTIFICATE":
+			if bag.certificate, err = x509.ParseCertificate(block.Bytes); err != nil {
+				return nil, err
+			}
+		case "PRIVATE KEY":
+			if bag.privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+				// EC keys, which cannot sign requests
+				if bag.privateKey, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+					return nil, err
+				}
+			}
+		}
+		bags = append(bags, bag)
+	}
+	return bags, nil
+}
+
+// The decodeChain decodes the single key of a PKCS#12 container along with
+// its certificate chain.
+func decodeChain(data []byte, password string) (*KeyEntry, error) {
+	privateKey, certificate, caCerts, err := pkcs12.DecodeChain(data, password)
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) || errors.Is(err, pkcs12.ErrDecryption) {
+		return nil, ErrIncorrectPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotRSAKey
+	}
+	return &KeyEntry{
+		PrivateKey:       rsaKey,
+		CertificateChain: append([]*x509.Certificate{certificate}, caCerts...),
+	}, nil
+}
+
+// The buildCertificateChain finds the certificate of the key, by local key ID
+// or by public key, and then its issuers.
+func buildCertificateChain(key *pkcs12Bag, privateKey *rsa.PrivateKey, certs []*pkcs12Bag) []*x509.Certificate {
+	var leaf *x509.Certificate
+	for _, cert := range certs {
+		if key.localKeyID != "" && cert.localKeyID == key.localKeyID {
+			leaf = cert.certificate
+			break
+		}
+	}
+	if leaf == nil {
+		for _, cert := range certs {
+			if privateKey.PublicKey.Equal(cert.certificate.PublicKey) {
+				leaf = cert.certificate
+				break
+			}
+		}
+	}
+	if leaf == nil {
+		return nil
+	}
+
+	chain := []*x509.Certificate{leaf}
+	for current := leaf; !bytes.Equal(current.RawIssuer, current.RawSubject); {
+		var issuer *x509.Certificate
+		for _, cert := range certs {
+			if bytes.Equal(cert.certificate.RawSubject, current.RawIssuer) && current.CheckSignatureFrom(cert.certificate) == nil {
+				issuer = cert.certificate
+				break
+			}
+		}
+		if issuer == nil || len(chain) > len(certs) {
+			break
+		}
+		chain = append(chain, issuer)
+		current = issuer
+	}
+	return chain
+}
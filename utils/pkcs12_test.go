@@ -0,0 +1,120 @@
+package utils_test
+
+import (
+	"errors"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"os"
+	"testing"
+)
+
+func TestLoadSigningKeyEntry_ShouldSupportModernContainers(t *testing.T) {
+
+	// GIVEN
+	expected, _ := utils.LoadSigningKey("../testdata/test_key_container.p12", "Password1")
+
+	// WHEN
+	entry, err := utils.LoadSigningKeyEntry("../testdata/test_key_container_aes.p12", "Password1", "")
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to load the key entry, but thrown %v", err)
+	}
+	if !entry.PrivateKey.Equal(expected) {
+		t.Errorf("Expected the key to match the legacy container key")
+	}
+	if entry.Alias != "mykeyalias" {
+		t.Errorf("Expected alias mykeyalias, got %v", entry.Alias)
+	}
+	if l := len(entry.CertificateChain); l != 2 {
+		t.Fatalf("Expected a chain of 2 certificates, got %v", l)
+	}
+	if cn := entry.CertificateChain[0].Subject.CommonName; cn != "MasterCardKey" {
+		t.Errorf("Expected the key certificate first, got %v", cn)
+	}
+	if cn := entry.CertificateChain[1].Subject.CommonName; cn != "Test CA" {
+		t.Errorf("Expected the CA certificate last, got %v", cn)
+	}
+}
+
+func TestLoadSigningKeyEntry_ShouldSupportLegacyContainers(t *testing.T) {
+
+	entry, err := utils.LoadSigningKeyEntry("../testdata/test_key_container.p12", "Password1", "MyKeyAlias")
+
+	if err != nil {
+		t.Fatalf("Expected to load the key entry, but thrown %v", err)
+	}
+	if l := len(entry.CertificateChain); l != 1 {
+		t.Errorf("Expected the self-signed certificate, got %v certificates", l)
+	}
+}
+
+func TestLoadSigningKeyEntry_ShouldSelectKeyByAlias(t *testing.T) {
+
+	// GIVEN
+	data, _ := os.ReadFile("../testdata/test_key_container_multi.p12")
+	expected, _ := utils.LoadSigningKey("../testdata/test_key_container.p12", "Password1")
+
+	// WHEN
+	first, err := utils.LoadSigningKeyEntryFromBytes(data, "Password1", "first")
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to load the first key, but thrown %v", err)
+	}
+	if !first.PrivateKey.Equal(expected) || first.Alias != "first" {
+		t.Errorf("Expected the first key, got %v", first.Alias)
+	}
+	if l := len(first.CertificateChain); l != 2 || first.CertificateChain[1].Subject.CommonName != "Test CA" {
+		t.Errorf("Expected the first key along with its chain, got %v certificates", l)
+	}
+
+	// WHEN
+	second, err := utils.LoadSigningKeyEntryFromBytes(data, "Password1", "SECOND")
+
+	// THEN
+	if err != nil {
+		t.Fatalf("Expected to load the second key, but thrown %v", err)
+	}
+	if second.PrivateKey.Equal(expected) || second.Alias != "second" {
+		t.Errorf("Expected the second key, got %v", second.Alias)
+	}
+	if l := len(second.CertificateChain); l != 1 || second.CertificateChain[0].Subject.CommonName != "Second Key" {
+		t.Errorf("Expected the certificate of the second key, got %v certificates", l)
+	}
+}
+
+func TestLoadSigningKeyEntry_ShouldRejectSmallKeys(t *testing.T) {
+
+	// GIVEN
+	path := "../testdata/test_key_container_1024.p12"
+
+	// WHEN
+	_, err := utils.LoadSigningKeyEntry(path, "Password1", "")
+	signingKey, legacyErr := utils.LoadSigningKey(path, "Password1")
+
+	// THEN
+	if !errors.Is(err, utils.ErrKeyTooSmall) {
+		t.Errorf("Expected ErrKeyTooSmall, got %v", err)
+	}
+	if legacyErr != nil || signingKey.N.BitLen() != 1024 {
+		t.Errorf("Expected LoadSigningKey to accept the 1024-bit key, got %v", legacyErr)
+	}
+}
+
+func TestLoadSigningKeyEntryInvalidInput(t *testing.T) {
+
+	data, _ := os.ReadFile("../testdata/test_key_container_multi.p12")
+
+	if _, err := utils.LoadSigningKeyEntryFromBytes(data, "Password1", ""); err == nil {
+		t.Errorf("Expected to throw error in case of several keys without alias")
+	}
+	if _, err := utils.LoadSigningKeyEntryFromBytes(data, "Password1", "unknown"); !errors.Is(err, utils.ErrAliasNotFound) {
+		t.Errorf("Expected ErrAliasNotFound, got %v", err)
+	}
+	if _, err := utils.LoadSigningKeyEntryFromBytes(data, "incorrect_password", "first"); !errors.Is(err, utils.ErrIncorrectPassword) {
+		t.Errorf("Expected ErrIncorrectPassword, got %v", err)
+	}
+	if _, err := utils.LoadSigningKeyEntryFromBytes([]byte("not a PKCS#12 container"), "Password1", ""); err == nil {
+		t.Errorf("Expected to throw error in case of invalid data")
+	}
+}
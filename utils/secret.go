@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	fileSecretPrefix   = "file://"
+	base64SecretPrefix = "base64:"
+)
+
+// ResolveSecret returns the value a secret reference points to. References
+// starting with "file://" are read from the file system, references starting
+// with "base64:" are base64 decoded and any other value is returned as is.
+func ResolveSecret(ref string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(ref, fileSecretPrefix):
+		return readFile(strings.TrimPrefix(ref, fileSecretPrefix))
+	case strings.HasPrefix(ref, base64SecretPrefix):
+		return base64.StdEncoding.DecodeString(strings.TrimPrefix(ref, base64SecretPrefix))
+	default:
+		return []byte(ref), nil
+	}
+}
+
+// ResolveSecretString returns the text value a secret reference points to,
+// without the trailing line break secret files usually end with. It suits
+// passwords and consumer keys, binary secrets are read with ResolveSecret.
+func ResolveSecretString(ref string) (string, error) {
+	value, err := ResolveSecret(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(value), "\r\n"), nil
+}
+
+// LoadSigningKeyFromBytes loads a RSA signing key out of either PEM data or
+// a PKCS#12 container. The alias is only used for PKCS#12 containers.
+func LoadSigningKeyFromBytes(data []byte, password, alias string) (*rsa.PrivateKey, error) {
+	if bytes.Contains(data, []byte("-----BEGIN ")) {
+		return LoadSigningKeyFromPEM(data, password)
+	}
+	entry, err := LoadSigningKeyEntryFromBytes(data, password, alias)
+	if err != nil {
+		return nil, err
+	}
+	return entry.PrivateKey, nil
+}
@@ -0,0 +1,64 @@
+package utils_test
+
+import (
+	"encoding/base64"
+	"github.com/mastercard/oauth1-signer-go/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+
+	data, _ := os.ReadFile("../testdata/test_key_container.p12")
+	tests := []struct {
+		ref      string
+		expected string
+	}{
+		{"file://../testdata/test_key_container.p12", string(data)},
+		{"base64:" + base64.StdEncoding.EncodeToString(data), string(data)},
+		{"literal value", "literal value"},
+	}
+	for _, test := range tests {
+		value, err := utils.ResolveSecret(test.ref)
+		if err != nil || string(value) != test.expected {
+			t.Errorf("Expected %.20q to be resolved, got %v", test.ref, err)
+		}
+	}
+
+	if _, err := utils.ResolveSecret("file://../testdata/invalidFile.p12"); err == nil {
+		t.Errorf("Expected to throw error in case of missing file")
+	}
+	if _, err := utils.ResolveSecret("base64:!"); err == nil {
+		t.Errorf("Expected to throw error in case of invalid base64 value")
+	}
+}
+
+func TestLoadSigningKeyFromBytes(t *testing.T) {
+
+	for _, path := range []string{"../testdata/test_key_container.p12", "../testdata/test_key_pkcs8_encrypted.pem"} {
+		data, _ := os.ReadFile(path)
+		privateKey, err := utils.LoadSigningKeyFromBytes(data, "Password1", "")
+		if err != nil || privateKey == nil {
+			t.Errorf("Expected to load RSA privateKey from %v, but thrown %v", path, err)
+		}
+	}
+}
+
+func TestResolveSecretString(t *testing.T) {
+
+	// GIVEN
+	path := filepath.Join(t.TempDir(), "key_password")
+	_ = os.WriteFile(path, []byte("Password1\r\n"), 0600)
+
+	// WHEN
+	value, err := utils.ResolveSecretString("file://" + path)
+
+	// THEN
+	if err != nil || value != "Password1" {
+		t.Errorf("Expected the trailing line break to be removed, got %q %v", value, err)
+	}
+	if _, err = utils.ResolveSecretString("file://../testdata/invalidFile.p12"); err == nil {
+		t.Errorf("Expected to throw error in case of missing file")
+	}
+}
@@ -0,0 +1,285 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/mastercard/oauth1-signer-go/crypto"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrMissingAuthorizationHeader is returned when the request has no
+	// Authorization header.
+	ErrMissingAuthorizationHeader = errors.New("verifier: missing authorization header")
+	// ErrMissingParameter is returned when a required oauth parameter is
+	// absent from the Authorization header.
+	ErrMissingParameter = errors.New("verifier: missing oauth parameter")
+	// ErrUnsupportedSignatureMethod is returned when oauth_signature_method
+	// is not supported.
+	ErrUnsupportedSignatureMethod = errors.New("verifier: unsupported signature method")
+	// ErrUnsupportedVersion is returned when oauth_version is present and
+	// is not "1.0".
+	ErrUnsupportedVersion = errors.New("verifier: unsupported oauth version")
+	// ErrBodyHashMismatch is returned when oauth_body_hash does not match
+	// the hash of the request payload.
+	ErrBodyHashMismatch = errors.New("verifier: body hash mismatch")
+	// ErrInvalidSignature is returned when oauth_signature cannot be
+	// verified against the signature base string.
+	ErrInvalidSignature = errors.New("verifier: invalid signature")
+)
+
+// KeyResolver resolves the public key of the consumer identified by
+// oauth_consumer_key, for services receiving requests from several
+// consumers. It returns a nil key when the consumer key is unknown, and
+// an error with a KeyRejected() bool method returning true when the
+// consumer key is known but rejected. Implementations must be safe for
+// concurrent use.
+type KeyResolver interface {
+	ResolveKey(ctx context.Context, consumerKey string) (*rsa.PublicKey, error)
+}
+
+// KeyResolverFunc adapts a function to the KeyResolver interface.
+type KeyResolverFunc func(ctx context.Context, consumerKey string) (*rsa.PublicKey, error)
+
+// ResolveKey returns f(ctx, consumerKey).
+func (f KeyResolverFunc) ResolveKey(ctx context.Context, consumerKey string) (*rsa.PublicKey, error) {
+	return f(ctx, consumerKey)
+}
+
+// Verifier represents the http request verifier that holds the
+// public key matching the consumer's signing key.
+type Verifier struct {
+	PublicKey *rsa.PublicKey
+	// Method, when set, verifies requests in place of the RSA-SHA256
+	// method using PublicKey, for instance HMACSHA1 with the consumer
+	// and token secrets. RSA methods without a key use PublicKey.
+	Method SignatureMethod
+	// HashFormBody expects application/x-www-form-urlencoded bodies to be
+	// hashed into oauth_body_hash, as done by a Signer with HashFormBody set.
+	HashFormBody bool
+	// NonceStore, when set, rejects the replay of a request with
+	// ErrNonceReused. The nonces of valid requests only are recorded.
+	NonceStore NonceStore
+	// TimestampWindow, when set, rejects requests whose oauth_timestamp
+	// is further than the window from the time of the verifier with
+	// ErrTimestampOutOfWindow. It defaults to DefaultTimestampWindow when
+	// NonceStore is set, since older requests could be replayed once their
+	// nonce has been forgotten.
+	TimestampWindow time.Duration
+	// Clock, when set, replaces the system clock.
+	Clock Clock
+}
+
+// Verify verifies the OAuth Authorization header of the http request. It
+// returns nil when the body hash and the signature are both valid.
+// The header, the timestamp and the nonce are checked before the body is
+// read.
+func (verifier *Verifier) Verify(req *http.Request) error {
+	_, err := verifier.VerifyWithDetails(req)
+	return err
+}
+
+// VerifyWithDetails verifies the http request like Verify and returns the
+// details recomputed from the request, for debugging requests that fail
+// verification. BodyHash is the hash of the body received and Signature
+// the oauth_signature of the header. The details are nil when the header
+// is missing, malformed or incomplete.
+func (verifier *Verifier) VerifyWithDetails(req *http.Request) (*SignatureDetails, error) {
+	signatureMethod := verifier.Method
+	if signatureMethod == nil {
+		if verifier.PublicKey == nil {
+			return nil, errors.New("verifier: provide valid public key")
+		}
+		signatureMethod = RSASHA256(nil, verifier.PublicKey)
+	} else if keyed, ok := signatureMethod.(keyedSignatureMethod); ok && verifier.PublicKey != nil {
+		signatureMethod = keyed.withKeys(nil, verifier.PublicKey)
+	}
+	if req == nil {
+		return nil, errors.New("verifier: Nil http.Request provided")
+	}
+	authHeader := req.Header.Get(AuthorizationHeaderName)
+	if authHeader == "" {
+		return nil, ErrMissingAuthorizationHeader
+	}
+	if err := verifier.checkHeader(authHeader); err != nil {
+		return nil, err
+	}
+	bodyHash, formParams, err := getRequestBodyParams(req, signatureMethod.HashAlgorithm(), verifier.HashFormBody)
+	if err != nil {
+		return nil, err
+	}
+	params, details, err := verifyAuthorizationHeader(authHeader, getRequestUrl(req), req.Method, bodyHash, formParams, signatureMethod)
+	if err != nil {
+		return details, err
+	}
+	return details, verifier.checkReplay(params)
+}
+
+// The checkHeader rejects a request whose Authorization header is invalid,
+// whose timestamp is out of the window, or whose nonce has been recorded
+// already, before its body is read.
+func (verifier *Verifier) checkHeader(authHeader string) error {
+	params, err := ParseAuthorizationHeader(authHeader)
+	if err != nil {
+		return err
+	}
+	if err = checkRequiredParams(params.Map()); err != nil {
+		return err
+	}
+	timestamp, err := verifier.checkTimestamp(params)
+	if err != nil {
+		return err
+	}
+	if store, ok := verifier.NonceStore.(nonceChecker); ok && store.seen(params.ConsumerKey(), params.Nonce(), timestamp) {
+		return ErrNonceReused
+	}
+	return nil
+}
+
+// The checkTimestamp checks the timestamp window, if any, and returns the
+// time of oauth_timestamp.
+func (verifier *Verifier) checkTimestamp(params *OAuthParams) (time.Time, error) {
+	window := verifier.TimestampWindow
+	if window == 0 && verifier.NonceStore != nil {
+		window = DefaultTimestampWindow
+	}
+	if window == 0 {
+		return time.Time{}, nil
+	}
+	seconds, err := strconv.ParseInt(params.Timestamp(), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrTimestampOutOfWindow, params.Timestamp())
+	}
+	timestamp := time.Unix(seconds, 0)
+	if skew := clockNow(verifier.Clock).Sub(timestamp); skew > window || skew < -window {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrTimestampOutOfWindow, params.Timestamp())
+	}
+	return timestamp, nil
+}
+
+// The checkReplay checks the timestamp window and records the nonce of a
+// verified request.
+func (verifier *Verifier) checkReplay(params *OAuthParams) error {
+	timestamp, err := verifier.checkTimestamp(params)
+	if err != nil || verifier.NonceStore == nil {
+		return err
+	}
+	return verifier.NonceStore.CheckAndStore(params.ConsumerKey(), params.Nonce(), timestamp)
+}
+
+// VerifyAuthorizationHeader checks a Mastercard API compliant OAuth Authorization
+// header against the request it was generated for.
+func VerifyAuthorizationHeader(authHeader string, u *url.URL, method string, payload []byte, publicKey *rsa.PublicKey) error {
+	return VerifyAuthorizationHeaderWithMethod(authHeader, u, method, payload, RSASHA256(nil, publicKey))
+}
+
+// VerifyAuthorizationHeaderWithMethod checks an OAuth Authorization header
+// signed with the given SignatureMethod against the request it was generated for.
+func VerifyAuthorizationHeaderWithMethod(authHeader string, u *url.URL, method string, payload []byte, signatureMethod SignatureMethod) error {
+	bodyHash, err := getBodyHash(payload, signatureMethod.HashAlgorithm())
+	if err != nil {
+		return err
+	}
+	_, _, err = verifyAuthorizationHeader(authHeader, u, method, bodyHash, nil, signatureMethod)
+	return err
+}
+
+// The verifyAuthorizationHeader checks the Authorization header against a
+// payload whose body hash has already been computed, or against the encoded
+// parameters of a form-encoded payload. It returns the parameters of the
+// verified header and the details of the signature, which are computed
+// before the body hash and the signature are checked.
+func verifyAuthorizationHeader(authHeader string, u *url.URL, method, bodyHash string, formParams map[string][]string, signatureMethod SignatureMethod) (*OAuthParams, *SignatureDetails, error) {
+	if err := checkSignatureMethod(u, signatureMethod); err != nil {
+		return nil, nil, err
+	}
+	params, err := ParseAuthorizationHeader(authHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+	oauthParams := params.Map()
+
+	if err = checkRequiredParams(oauthParams); err != nil {
+		return nil, nil, err
+	}
+	if m := oauthParams[oauthSignatureMethodParam]; m != signatureMethod.Name() {
+		return nil, nil, fmt.Errorf("%w: %v", ErrUnsupportedSignatureMethod, m)
+	}
+	if v, ok := oauthParams[oauthVersionParam]; ok && v != defaultOauthVersion {
+		return nil, nil, fmt.Errorf("%w: %v", ErrUnsupportedVersion, v)
+	}
+	receivedBodyHash, hasBodyHash := oauthParams[oauthBodyHashParam]
+
+	// the signature itself is not part of the signature base string
+	signature := oauthParams[oauthSignatureParam]
+	delete(oauthParams, oauthSignatureParam)
+	for k, v := range oauthParams {
+		oauthParams[k] = percentEncode(v)
+	}
+	details := getSignatureDetails(u, method, bodyHash, formParams, oauthParams)
+	details.Signature = signature
+
+	// body hash, which must not be sent for form-encoded payloads
+	if bodyHash == "" {
+		if hasBodyHash {
+			return nil, details, fmt.Errorf("%w: unexpected for form-encoded body", ErrBodyHashMismatch)
+		}
+	} else if !hasBodyHash {
+		return nil, details, fmt.Errorf("%w: %v", ErrMissingParameter, oauthBodyHashParam)
+	} else if subtle.ConstantTimeCompare([]byte(bodyHash), []byte(receivedBodyHash)) != 1 {
+		return nil, details, ErrBodyHashMismatch
+	}
+
+	if err = signatureMethod.Verify(details.SignatureBaseString, signature); err != nil {
+		return nil, details, err
+	}
+	return params, details, nil
+}
+
+// The checkRequiredParams checks that all parameters produced by
+// getOAuthParams are present.
+func checkRequiredParams(oauthParams map[string]string) error {
+	for _, name := range []string{oauthConsumerKeyParam, oauthNonceParam, oauthSignatureMethodParam,
+		oauthTimestampParam, oauthSignatureParam} {
+		if _, ok := oauthParams[name]; !ok {
+			return fmt.Errorf("%w: %v", ErrMissingParameter, name)
+		}
+	}
+	return nil
+}
+
+// The verifySignatureBaseString performs the RSA verification of the
+// given base64 encoded signature with the given hash algorithm.
+func verifySignatureBaseString(sbs, signature string, publicKey *rsa.PublicKey, algorithm string) error {
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if err := crypto.VerifyWithAlgorithm([]byte(sbs), decoded, publicKey, algorithm); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// The getRequestUrl returns the absolute URL of the request. Server side
+// requests only carry the path and query, hence the host and scheme are
+// taken from the request itself.
+func getRequestUrl(req *http.Request) *url.URL {
+	if req.URL.IsAbs() {
+		return req.URL
+	}
+	u := *req.URL
+	u.Host = req.Host
+	u.Scheme = "http"
+	if req.TLS != nil {
+		u.Scheme = "https"
+	}
+	return &u
+}
@@ -0,0 +1,129 @@
+package oauth_test
+
+import (
+	"bytes"
+	"errors"
+	oauth "github.com/mastercard/oauth1-signer-go"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHttpRequestVerification(t *testing.T) {
+
+	// GIVEN
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service?a=1&b=%3A", bytes.NewBufferString("{\"foo\":\"bår\"}"))
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}
+	_ = signer.Sign(req)
+
+	// WHEN
+	verifier := &oauth.Verifier{PublicKey: &signingKey.PublicKey}
+	err := verifier.Verify(req)
+
+	// THEN
+	if err != nil {
+		t.Errorf("Expected the request to be verified, got %v", err)
+	}
+}
+
+func TestHttpRequestVerification_ShouldSupportServerSideRequests(t *testing.T) {
+
+	// GIVEN
+	clientReq, _ := http.NewRequest("GET", "http://example.com/service?offset=0", nil)
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}
+	_ = signer.Sign(clientReq)
+	serverReq := httptest.NewRequest("GET", "/service?offset=0", nil)
+	serverReq.Host = "example.com"
+	serverReq.Header.Set(oauth.AuthorizationHeaderName, clientReq.Header.Get(oauth.AuthorizationHeaderName))
+
+	// WHEN
+	verifier := &oauth.Verifier{PublicKey: &signingKey.PublicKey}
+	err := verifier.Verify(serverReq)
+
+	// THEN
+	if err != nil {
+		t.Errorf("Expected the request to be verified, got %v", err)
+	}
+}
+
+func TestHttpRequestVerification_ShouldReturnDetailsOfFailedVerification(t *testing.T) {
+
+	// GIVEN
+	req, _ := http.NewRequest("POST", "https://sandbox.api.mastercard.com/service?a=1", strings.NewReader("payload"))
+	signer := &oauth.Signer{ConsumerKey: consumerKey, SigningKey: signingKey}
+	signed, _ := signer.SignWithDetails(req)
+	req.Body = io.NopCloser(strings.NewReader("tampered"))
+	req.GetBody = nil
+	verifier := &oauth.Verifier{PublicKey: &signingKey.PublicKey}
+
+	// WHEN
+	details, err := verifier.VerifyWithDetails(req)
+
+	// THEN
+	if !errors.Is(err, oauth.ErrBodyHashMismatch) || details == nil {
+		t.Fatalf("Expected ErrBodyHashMismatch with details, got %v", err)
+	}
+	if details.BodyHash == signed.BodyHash {
+		t.Errorf("Expected the hash of the body received, got %v", details.BodyHash)
+	}
+	if details.SignatureBaseString != signed.SignatureBaseString || details.Signature != signed.Signature {
+		t.Errorf("Expected the signature base string signed, got %v", details.SignatureBaseString)
+	}
+	if _, err = verifier.VerifyWithDetails(httptest.NewRequest("GET", "/", nil)); err == nil {
+		t.Errorf("Expected an error in case of missing header")
+	}
+}
+
+func TestVerifyAuthorizationHeader_ShouldReturnTypedErrors(t *testing.T) {
+	u, _ := url.Parse("https://sandbox.api.mastercard.com/service")
+	payload := []byte("payload")
+	authHeader, _ := oauth.GetAuthorizationHeader(u, "POST", payload, consumerKey, signingKey)
+	otherUrl, _ := url.Parse("https://sandbox.api.mastercard.com/service?extra=1")
+
+	tests := []struct {
+		name       string
+		authHeader string
+		url        *url.URL
+		method     string
+		payload    []byte
+		expected   error
+	}{
+		{"malformed header", "Bearer token", u, "POST", payload, oauth.ErrMalformedAuthorizationHeader},
+		{"missing parameter", strings.Replace(authHeader, "oauth_nonce", "oauth_other", 1), u, "POST", payload, oauth.ErrMissingParameter},
+		{"unsupported method", strings.Replace(authHeader, "RSA-SHA256", "HMAC-SHA1", 1), u, "POST", payload, oauth.ErrUnsupportedSignatureMethod},
+		{"unsupported version", strings.Replace(authHeader, "oauth_version=\"1.0\"", "oauth_version=\"2.0\"", 1), u, "POST", payload, oauth.ErrUnsupportedVersion},
+		{"tampered body", authHeader, u, "POST", []byte("tampered"), oauth.ErrBodyHashMismatch},
+		{"tampered method", authHeader, u, "PUT", payload, oauth.ErrInvalidSignature},
+		{"tampered url", authHeader, otherUrl, "POST", payload, oauth.ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		err := oauth.VerifyAuthorizationHeader(test.authHeader, test.url, test.method, test.payload, &signingKey.PublicKey)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestHttpRequestVerificationWithInvalidInput(t *testing.T) {
+
+	// verify with nil public key
+	verifier := &oauth.Verifier{}
+	if err := verifier.Verify(request); err == nil {
+		t.Errorf("Expected to thrown an error in case of invalid public key")
+	}
+
+	// verify with nil http.Request
+	verifier = &oauth.Verifier{PublicKey: &signingKey.PublicKey}
+	if err := verifier.Verify(nil); err == nil {
+		t.Errorf("Expected to thrown an error in case of Nil request")
+	}
+
+	// verify without Authorization header
+	getRequest, _ := http.NewRequest("GET", "https://sandbox.api.mastercard.com/service", nil)
+	if err := verifier.Verify(getRequest); !errors.Is(err, oauth.ErrMissingAuthorizationHeader) {
+		t.Errorf("Expected ErrMissingAuthorizationHeader, got %v", err)
+	}
+}